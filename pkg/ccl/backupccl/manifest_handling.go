@@ -265,7 +265,7 @@ func readBackupManifest(
 				err, "file appears encrypted -- try specifying one of \"%s\" or \"%s\"",
 				backupOptEncPassphrase, backupOptEncKMS)
 		}
-		return BackupManifest{}, err
+		return BackupManifest{}, errors.Wrap(err, "unrecognized manifest format")
 	}
 	for _, d := range backupManifest.Descriptors {
 		// Calls to GetTable are generally frowned upon.
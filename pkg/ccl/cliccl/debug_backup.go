@@ -43,6 +43,7 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/colinfo"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
 	"github.com/cockroachdb/cockroach/pkg/sql/row"
 	"github.com/cockroachdb/cockroach/pkg/sql/rowenc"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
@@ -144,6 +145,7 @@ func setDebugContextDefault() {
 }
 
 func init() {
+	doctor.BackupManifestReader = readBackupManifestDescriptors
 
 	showCmd := &cobra.Command{
 		Use:   "show <backup_path>",
@@ -169,6 +171,14 @@ func init() {
 		RunE:  clierrorplus.MaybeDecorateError(runListIncrementalCmd),
 	}
 
+	examineCmd := &cobra.Command{
+		Use:   "examine <backup_path>",
+		Short: "run doctor checks on the descriptors of a backup",
+		Long:  "Runs the checks of the doctor tool on the descriptors of a SQL backup.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  clierrorplus.MaybeDecorateError(runExamineCmd),
+	}
+
 	exportDataCmd := &cobra.Command{
 		Use:   "export <backup_path>",
 		Short: "export table data from a backup",
@@ -257,6 +267,7 @@ func init() {
 		showCmd,
 		listBackupsCmd,
 		listIncrementalCmd,
+		examineCmd,
 		exportDataCmd,
 	}
 
@@ -303,6 +314,18 @@ func getManifestFromURI(ctx context.Context, path string) (backupccl.BackupManif
 	return backupManifest, nil
 }
 
+// readBackupManifestDescriptors reads the descriptors and end time of the
+// backup at path for the doctor.
+func readBackupManifestDescriptors(
+	ctx context.Context, path string,
+) ([]descpb.Descriptor, hlc.Timestamp, error) {
+	backupManifest, err := getManifestFromURI(ctx, path)
+	if err != nil {
+		return nil, hlc.Timestamp{}, err
+	}
+	return backupManifest.Descriptors, backupManifest.EndTime, nil
+}
+
 func runShowCmd(cmd *cobra.Command, args []string) error {
 
 	path := args[0]
@@ -322,6 +345,21 @@ func runShowCmd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runExamineCmd(cmd *cobra.Command, args []string) error {
+
+	path := args[0]
+	ctx := context.Background()
+	ok, err := doctor.ExamineBackup(ctx, path, os.Stdout)
+	if err != nil {
+		return errors.Wrapf(err, "examining backup")
+	}
+	if !ok {
+		return errors.New("validation failed")
+	}
+	fmt.Println("No problems found!")
+	return nil
+}
+
 func runListBackupsCmd(cmd *cobra.Command, args []string) error {
 
 	path := args[0]
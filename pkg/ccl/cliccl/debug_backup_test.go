@@ -12,6 +12,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -237,6 +240,50 @@ func TestListIncremental(t *testing.T) {
 	checkExpectedOutput(t, buf.String(), out)
 }
 
+func TestExamineBackup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	c := cli.NewCLITest(cli.TestCLIParams{T: t, NoServer: true})
+	defer c.Cleanup()
+
+	ctx := context.Background()
+	dir, cleanFn := testutils.TempDir(t)
+	defer cleanFn()
+	srv, db, _ := serverutils.StartServer(t, base.TestServerArgs{ExternalIODir: dir, Insecure: true})
+	defer srv.Stopper().Stop(ctx)
+
+	sqlDB := sqlutils.MakeSQLRunner(db)
+	sqlDB.Exec(t, `CREATE DATABASE testDB`)
+	sqlDB.Exec(t, `USE testDB`)
+	sqlDB.Exec(t, `CREATE TYPE fooType AS ENUM ('a')`)
+	sqlDB.Exec(t, `CREATE TABLE fooTable (a INT PRIMARY KEY, b fooType)`)
+	const backupPath = "nodelocal://0/fooFolder"
+	sqlDB.Exec(t, `BACKUP DATABASE testDB TO $1`, backupPath)
+
+	setDebugContextDefault()
+	out, err := c.RunWithCapture(fmt.Sprintf("debug backup examine %s --external-io-dir=%s", backupPath, dir))
+	require.NoError(t, err)
+	require.Contains(t, out, "No problems found!")
+
+	// A foreign key to a table outside the backup is only a warning.
+	sqlDB.Exec(t, `CREATE TABLE barTable (a INT PRIMARY KEY REFERENCES fooTable (a))`)
+	const tableBackupPath = "nodelocal://0/barFolder"
+	sqlDB.Exec(t, `BACKUP TABLE barTable TO $1`, tableBackupPath)
+	out, err = c.RunWithCapture(fmt.Sprintf("debug backup examine %s --external-io-dir=%s", tableBackupPath, dir))
+	require.NoError(t, err)
+	require.Contains(t, out, "warning: ")
+	require.Contains(t, out, "No problems found!")
+
+	// A manifest which doesn't decode is reported as such.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "garbage"), 0755))
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(dir, "garbage", "BACKUP_MANIFEST"), []byte("not a manifest"), 0644))
+	out, err = c.RunWithCapture(fmt.Sprintf("debug backup examine nodelocal://0/garbage --external-io-dir=%s", dir))
+	require.NoError(t, err)
+	require.Contains(t, out, "unrecognized manifest format")
+}
+
 func TestExportData(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -11,35 +11,19 @@
 package cli
 
 import (
-	"bufio"
 	"context"
-	"database/sql/driver"
-	hx "encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path"
-	"strconv"
-	"strings"
-	"time"
 
-	apd "github.com/cockroachdb/apd/v2"
 	"github.com/cockroachdb/cockroach/pkg/cli/clierror"
 	"github.com/cockroachdb/cockroach/pkg/cli/clierrorplus"
 	"github.com/cockroachdb/cockroach/pkg/cli/clisqlclient"
 	"github.com/cockroachdb/cockroach/pkg/cli/exit"
-	"github.com/cockroachdb/cockroach/pkg/jobs"
-	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
-	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
 	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
-	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
 	"github.com/cockroachdb/cockroach/pkg/sql/sem/builtins"
-	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
-	"github.com/cockroachdb/cockroach/pkg/util/hlc"
-	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
-	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
 	"github.com/cockroachdb/errors"
-	"github.com/lib/pq"
 	"github.com/spf13/cobra"
 )
 
@@ -99,7 +83,11 @@ requires the path of the unzipped 'debug' directory as its argument.
 	}
 }
 
-func makeClusterCommand(fn doctorFn) *cobra.Command {
+// clusterFn is the function a cluster command runs on the connection to the
+// cluster.
+type clusterFn = func(ctx context.Context, q doctor.Queryer, out io.Writer) (err error)
+
+func makeClusterCommand(fn clusterFn) *cobra.Command {
 	return &cobra.Command{
 		Use:   "cluster --url=<cluster connection string>",
 		Short: "run doctor tool on live cockroach cluster",
@@ -114,26 +102,48 @@ Run the doctor tool system data from a live cluster specified by --url.
 					return errors.Wrap(err, "could not establish connection to cluster")
 				}
 				defer func() { resErr = errors.CombineErrors(resErr, sqlConn.Close()) }()
-				descs, ns, jobs, err := fromCluster(sqlConn, cliCtx.cmdTimeout)
-				if err != nil {
-					return err
+				q := doctorQueryer{conn: sqlConn}
+				if timeout := cliCtx.cmdTimeout; timeout != 0 {
+					stmt := fmt.Sprintf(`SET statement_timeout = '%s'`, timeout)
+					if err := sqlConn.Exec(q.maybePrint(stmt), nil); err != nil {
+						return err
+					}
 				}
-				return fn(descs, ns, jobs, os.Stdout)
+				return fn(context.Background(), q, os.Stdout)
 			}),
 	}
 }
 
+// doctorQueryer reads the system tables for the doctor through the connection
+// of a cluster command.
+type doctorQueryer struct {
+	conn clisqlclient.Conn
+}
+
+// maybePrint prints stmt in verbose mode, and returns it.
+func (q doctorQueryer) maybePrint(stmt string) string {
+	if debugCtx.verbose {
+		fmt.Println("querying " + stmt)
+	}
+	return stmt
+}
+
+// Query implements doctor.Queryer.
+func (q doctorQueryer) Query(_ context.Context, query string) (doctor.Rows, error) {
+	return q.conn.Query(q.maybePrint(query), nil)
+}
+
 func deprecateCommand(cmd *cobra.Command) *cobra.Command {
 	cmd.Hidden = true
 	cmd.Deprecated = fmt.Sprintf("use 'doctor examine %s' instead.", cmd.Name())
 	return cmd
 }
 
-var doctorExamineClusterCmd = makeClusterCommand(runDoctorExamine)
+var doctorExamineClusterCmd = makeClusterCommand(runDoctorExamineCluster)
 var doctorExamineZipDirCmd = makeZipDirCommand(runDoctorExamine)
-var doctorExamineFallbackClusterCmd = deprecateCommand(makeClusterCommand(runDoctorExamine))
+var doctorExamineFallbackClusterCmd = deprecateCommand(makeClusterCommand(runDoctorExamineCluster))
 var doctorExamineFallbackZipDirCmd = deprecateCommand(makeZipDirCommand(runDoctorExamine))
-var doctorRecreateClusterCmd = makeClusterCommand(runDoctorRecreate)
+var doctorRecreateClusterCmd = makeClusterCommand(runDoctorRecreateCluster)
 var doctorRecreateZipDirCmd = makeZipDirCommand(runDoctorRecreate)
 
 func runDoctorRecreate(
@@ -145,15 +155,33 @@ func runDoctorRecreate(
 	return doctor.DumpSQL(out, descTable, namespaceTable)
 }
 
+func runDoctorRecreateCluster(ctx context.Context, q doctor.Queryer, out io.Writer) error {
+	descTable, namespaceTable, jobsTable, err := doctor.FromQueryer(ctx, q, out)
+	if err != nil {
+		return err
+	}
+	return runDoctorRecreate(descTable, namespaceTable, jobsTable, out)
+}
+
 func runDoctorExamine(
 	descTable doctor.DescriptorTable,
 	namespaceTable doctor.NamespaceTable,
 	jobsTable doctor.JobsTable,
 	out io.Writer,
 ) (err error) {
-	var valid bool
-	valid, err = doctor.Examine(
+	valid, err := doctor.Examine(
 		context.Background(), descTable, namespaceTable, jobsTable, debugCtx.verbose, out)
+	return doctorExamineResult(valid, err, out)
+}
+
+func runDoctorExamineCluster(ctx context.Context, q doctor.Queryer, out io.Writer) error {
+	valid, err := doctor.ExamineFromQueryer(ctx, q, debugCtx.verbose, out)
+	return doctorExamineResult(valid, err, out)
+}
+
+// doctorExamineResult turns the result of an examination into the result of
+// the command.
+func doctorExamineResult(valid bool, err error, out io.Writer) error {
 	if err != nil {
 		return err
 	}
@@ -165,152 +193,6 @@ func runDoctorExamine(
 	return nil
 }
 
-// fromCluster collects system table data from a live cluster.
-func fromCluster(
-	sqlConn clisqlclient.Conn, timeout time.Duration,
-) (
-	descTable doctor.DescriptorTable,
-	namespaceTable doctor.NamespaceTable,
-	jobsTable doctor.JobsTable,
-	retErr error,
-) {
-	maybePrint := func(stmt string) string {
-		if debugCtx.verbose {
-			fmt.Println("querying " + stmt)
-		}
-		return stmt
-	}
-	if timeout != 0 {
-		stmt := fmt.Sprintf(`SET statement_timeout = '%s'`, timeout)
-		if err := sqlConn.Exec(maybePrint(stmt), nil); err != nil {
-			return nil, nil, nil, err
-		}
-	}
-	stmt := `
-SELECT id, descriptor, crdb_internal_mvcc_timestamp AS mod_time_logical
-FROM system.descriptor ORDER BY id`
-	checkColumnExistsStmt := "SELECT crdb_internal_mvcc_timestamp FROM system.descriptor LIMIT 1"
-	_, err := sqlConn.QueryRow(maybePrint(checkColumnExistsStmt), nil)
-	// On versions before 20.2, the system.descriptor won't have the builtin
-	// crdb_internal_mvcc_timestamp. If we can't find it, use NULL instead.
-	if pqErr := (*pq.Error)(nil); errors.As(err, &pqErr) {
-		if pgcode.MakeCode(string(pqErr.Code)) == pgcode.UndefinedColumn {
-			stmt = `
-SELECT id, descriptor, NULL AS mod_time_logical
-FROM system.descriptor ORDER BY id`
-		}
-	} else if err != nil {
-		return nil, nil, nil, err
-	}
-	descTable = make([]doctor.DescriptorTableRow, 0)
-
-	if err := selectRowsMap(sqlConn, maybePrint(stmt), make([]driver.Value, 3), func(vals []driver.Value) error {
-		var row doctor.DescriptorTableRow
-		if id, ok := vals[0].(int64); ok {
-			row.ID = id
-		} else {
-			return errors.Errorf("unexpected value: %T of %v", vals[0], vals[0])
-		}
-		if descBytes, ok := vals[1].([]byte); ok {
-			row.DescBytes = descBytes
-		} else {
-			return errors.Errorf("unexpected value: %T of %v", vals[1], vals[1])
-		}
-		if vals[2] == nil {
-			row.ModTime = hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
-		} else if mt, ok := vals[2].([]byte); ok {
-			decimal, _, err := apd.NewFromString(string(mt))
-			if err != nil {
-				return err
-			}
-			ts, err := tree.DecimalToHLC(decimal)
-			if err != nil {
-				return err
-			}
-			row.ModTime = ts
-		} else {
-			return errors.Errorf("unexpected value: %T of %v", vals[2], vals[2])
-		}
-		descTable = append(descTable, row)
-		return nil
-	}); err != nil {
-		return nil, nil, nil, err
-	}
-
-	stmt = `SELECT "parentID", "parentSchemaID", name, id FROM system.namespace`
-
-	checkColumnExistsStmt = `SELECT "parentSchemaID" FROM system.namespace LIMIT 1`
-	_, err = sqlConn.QueryRow(maybePrint(checkColumnExistsStmt), nil)
-	// On versions before 20.1, table system.namespace does not have this column.
-	// In that case the ParentSchemaID for tables is 29 and for databases is 0.
-	if pqErr := (*pq.Error)(nil); errors.As(err, &pqErr) {
-		if pgcode.MakeCode(string(pqErr.Code)) == pgcode.UndefinedColumn {
-			stmt = `
-SELECT "parentID", CASE WHEN "parentID" = 0 THEN 0 ELSE 29 END AS "parentSchemaID", name, id
-FROM system.namespace`
-		}
-	} else if err != nil {
-		return nil, nil, nil, err
-	}
-
-	namespaceTable = make([]doctor.NamespaceTableRow, 0)
-	if err := selectRowsMap(sqlConn, maybePrint(stmt), make([]driver.Value, 4), func(vals []driver.Value) error {
-		var row doctor.NamespaceTableRow
-		if parentID, ok := vals[0].(int64); ok {
-			row.ParentID = descpb.ID(parentID)
-		} else {
-			return errors.Errorf("unexpected value: %T of %v", vals[0], vals[0])
-		}
-		if schemaID, ok := vals[1].(int64); ok {
-			row.ParentSchemaID = descpb.ID(schemaID)
-		} else {
-			return errors.Errorf("unexpected value: %T of %v", vals[0], vals[0])
-		}
-		if name, ok := vals[2].(string); ok {
-			row.Name = name
-		} else {
-			return errors.Errorf("unexpected value: %T of %v", vals[1], vals[1])
-		}
-		if id, ok := vals[3].(int64); ok {
-			row.ID = id
-		} else {
-			return errors.Errorf("unexpected value: %T of %v", vals[0], vals[0])
-		}
-		namespaceTable = append(namespaceTable, row)
-		return nil
-	}); err != nil {
-		return nil, nil, nil, err
-	}
-
-	stmt = `SELECT id, status, payload, progress FROM system.jobs`
-	jobsTable = make(doctor.JobsTable, 0)
-
-	if err := selectRowsMap(sqlConn, maybePrint(stmt), make([]driver.Value, 4), func(vals []driver.Value) error {
-		md := jobs.JobMetadata{}
-		md.ID = jobspb.JobID(vals[0].(int64))
-		md.Status = jobs.Status(vals[1].(string))
-		md.Payload = &jobspb.Payload{}
-		if err := protoutil.Unmarshal(vals[2].([]byte), md.Payload); err != nil {
-			return err
-		}
-		md.Progress = &jobspb.Progress{}
-		// Progress is a nullable column, so have to check for nil here.
-		progressBytes, ok := vals[3].([]byte)
-		if !ok {
-			return errors.Errorf("unexpected NULL progress on job row: %v", md)
-		}
-		if err := protoutil.Unmarshal(progressBytes, md.Progress); err != nil {
-			return err
-		}
-		jobsTable = append(jobsTable, md)
-		return nil
-	}); err != nil {
-		return nil, nil, nil, err
-	}
-
-	return descTable, namespaceTable, jobsTable, nil
-}
-
 // fromZipDir collects system table data from a decompressed debug zip dir.
 func fromZipDir(
 	zipDirPath string,
@@ -323,22 +205,9 @@ func fromZipDir(
 	// To make parsing user functions code happy.
 	_ = builtins.AllBuiltinNames
 
-	descTable = make(doctor.DescriptorTable, 0)
-	if err := slurp(zipDirPath, "system.descriptor.txt", func(row string) error {
-		fields := strings.Fields(row)
-		last := len(fields) - 1
-		i, err := strconv.Atoi(fields[0])
-		if err != nil {
-			return errors.Wrapf(err, "failed to parse descriptor id %s", fields[0])
-		}
-
-		descBytes, err := hx.DecodeString(fields[last])
-		if err != nil {
-			return errors.Wrapf(err, "failed to decode hex descriptor %d", i)
-		}
-		ts := hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
-		descTable = append(descTable, doctor.DescriptorTableRow{ID: int64(i), DescBytes: descBytes, ModTime: ts})
-		return nil
+	if err := slurp(zipDirPath, "system.descriptor.txt", func(in io.Reader) (err error) {
+		descTable, err = doctor.ReadDescriptorTable(in)
+		return err
 	}); err != nil {
 		return nil, nil, nil, err
 	}
@@ -353,69 +222,16 @@ func fromZipDir(
 		namespaceFileName = "system.namespace.txt"
 	}
 
-	namespaceTable = make(doctor.NamespaceTable, 0)
-	if err := slurp(zipDirPath, namespaceFileName, func(row string) error {
-		fields := strings.Fields(row)
-		parID, err := strconv.Atoi(fields[0])
-		if err != nil {
-			return errors.Wrapf(err, "failed to parse parent id %s", fields[0])
-		}
-		parSchemaID, err := strconv.Atoi(fields[1])
-		if err != nil {
-			return errors.Wrapf(err, "failed to parse parent schema id %s", fields[1])
-		}
-		id, err := strconv.Atoi(fields[3])
-		if err != nil {
-			if fields[3] == "NULL" {
-				id = int(descpb.InvalidID)
-			} else {
-				return errors.Wrapf(err, "failed to parse id %s", fields[3])
-			}
-		}
-
-		namespaceTable = append(namespaceTable, doctor.NamespaceTableRow{
-			NameInfo: descpb.NameInfo{
-				ParentID: descpb.ID(parID), ParentSchemaID: descpb.ID(parSchemaID), Name: fields[2],
-			},
-			ID: int64(id),
-		})
-		return nil
+	if err := slurp(zipDirPath, namespaceFileName, func(in io.Reader) (err error) {
+		namespaceTable, err = doctor.ReadNamespaceTable(in)
+		return err
 	}); err != nil {
 		return nil, nil, nil, err
 	}
 
-	jobsTable = make(doctor.JobsTable, 0)
-	if err := slurp(zipDirPath, "system.jobs.txt", func(row string) error {
-		fields := strings.Fields(row)
-		md := jobs.JobMetadata{}
-		md.Status = jobs.Status(fields[1])
-
-		id, err := strconv.Atoi(fields[0])
-		if err != nil {
-			return errors.Wrapf(err, "failed to parse job id %s", fields[0])
-		}
-		md.ID = jobspb.JobID(id)
-
-		last := len(fields) - 1
-		payloadBytes, err := hx.DecodeString(fields[last-1])
-		if err != nil {
-			return errors.Wrapf(err, "job %d: failed to decode hex payload", id)
-		}
-		md.Payload = &jobspb.Payload{}
-		if err := protoutil.Unmarshal(payloadBytes, md.Payload); err != nil {
-			return errors.Wrap(err, "failed unmarshalling job payload")
-		}
-		progressBytes, err := hx.DecodeString(fields[last])
-		if err != nil {
-			return errors.Wrapf(err, "job %d: failed to decode hex progress", id)
-		}
-		md.Progress = &jobspb.Progress{}
-		if err := protoutil.Unmarshal(progressBytes, md.Progress); err != nil {
-			return errors.Wrap(err, "failed unmarshalling job progress")
-		}
-
-		jobsTable = append(jobsTable, md)
-		return nil
+	if err := slurp(zipDirPath, "system.jobs.txt", func(in io.Reader) (err error) {
+		jobsTable, err = doctor.ReadJobsTable(in)
+		return err
 	}); err != nil {
 		return nil, nil, nil, err
 	}
@@ -424,7 +240,7 @@ func fromZipDir(
 }
 
 // slurp reads a file in zipDirPath and processes its contents.
-func slurp(zipDirPath string, fileName string, tableMapFn func(row string) error) error {
+func slurp(zipDirPath string, fileName string, readFn func(in io.Reader) error) error {
 	filePath := path.Join(zipDirPath, fileName)
 
 	// Check for existence of companion .err.txt file.
@@ -445,46 +261,5 @@ func slurp(zipDirPath string, fileName string, tableMapFn func(row string) error
 	if debugCtx.verbose {
 		fmt.Println("reading " + filePath)
 	}
-	return tableMap(f, tableMapFn)
-}
-
-// tableMap applies `fn` to all rows in `in`.
-func tableMap(in io.Reader, fn func(string) error) error {
-	firstLine := true
-	sc := bufio.NewScanner(in)
-	// Read lines up to 50 MB in size.
-	sc.Buffer(make([]byte, 64*1024), 50*1024*1024)
-	for sc.Scan() {
-		if firstLine {
-			firstLine = false
-			continue
-		}
-		if err := fn(sc.Text()); err != nil {
-			return err
-		}
-	}
-	return sc.Err()
-}
-
-// selectRowsMap applies `fn` to all rows returned from a select statement.
-func selectRowsMap(
-	conn clisqlclient.Conn, stmt string, vals []driver.Value, fn func([]driver.Value) error,
-) error {
-	rows, err := conn.Query(stmt, nil)
-	if err != nil {
-		return errors.Wrapf(err, "query '%s'", stmt)
-	}
-	for {
-		var err error
-		if err = rows.Next(vals); err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
-		}
-		if err := fn(vals); err != nil {
-			return err
-		}
-	}
-	return nil
+	return errors.Wrapf(readFn(f), "reading %s", filePath)
 }
@@ -0,0 +1,75 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// BackupManifestReader reads the manifest of the backup at manifestPath and
+// returns its descriptors and end time. Reading a backup manifest takes the
+// backupccl package, which this one can't depend on: the reader is set by
+// pkg/ccl/cliccl, and ExamineBackup is only available when it's linked in.
+var BackupManifestReader func(
+	ctx context.Context, manifestPath string,
+) ([]descpb.Descriptor, hlc.Timestamp, error)
+
+// ExamineBackup runs the same suite of checks as ExamineDescriptors over the
+// descriptors of the backup whose manifest is at manifestPath. A backup has no
+// namespace table, so that namespace entries aren't checked, nor does it hold
+// the descriptors it doesn't cover, so that references to missing descriptors
+// are only warnings. The MVCC timestamps of the descriptors are taken to be
+// the end time of the backup.
+func ExamineBackup(ctx context.Context, manifestPath string, w io.Writer) (ok bool, err error) {
+	if BackupManifestReader == nil {
+		return false, errors.New("examining a backup requires a CCL binary")
+	}
+	descs, endTime, err := BackupManifestReader(ctx, manifestPath)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read backup manifest %s", manifestPath)
+	}
+	if endTime.IsEmpty() {
+		return false, errors.Newf("unrecognized manifest format: %s has no end time", manifestPath)
+	}
+	descTable := make(DescriptorTable, 0, len(descs))
+	for i := range descs {
+		id, _, _, _, _, err := descpb.GetDescriptorMetadata(&descs[i])
+		if err != nil {
+			return false, errors.Wrapf(err,
+				"unrecognized manifest format: descriptor at position %d in %s", i, manifestPath)
+		}
+		descBytes, err := protoutil.Marshal(&descs[i])
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to marshal descriptor %d", id)
+		}
+		descTable = append(descTable,
+			DescriptorTableRow{ID: int64(id), DescBytes: descBytes, ModTime: endTime})
+	}
+
+	e := newExamination(nil /* opts */)
+	e.partial = true
+	fmt.Fprintf(w, "Examining %d descriptors of backup %s...\n", len(descTable), manifestPath)
+	err = examineDescriptors(
+		ctx, e, descTable, nil /* namespaceTable */, nil /* jmg */, nil /* schemaChanging */)
+	e.writeBriefText(w)
+	e.writeSummary(w)
+	if err != nil {
+		return false, err
+	}
+	return !e.hasErrors(), nil
+}
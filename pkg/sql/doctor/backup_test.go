@@ -0,0 +1,102 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExamineBackup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	ctx := context.Background()
+	dbDesc := &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+		Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+	}}
+	droppedTableDesc := modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+		tbl.Name = "u"
+		tbl.ID = 53
+		tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("u")
+		tbl.State = descpb.DescriptorState_DROP
+	})
+	fkTableDesc := modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+		tbl.OutboundFKs = []descpb.ForeignKeyConstraint{{
+			Name:                "fk",
+			OriginTableID:       51,
+			OriginColumnIDs:     []descpb.ColumnID{1},
+			ReferencedTableID:   60,
+			ReferencedColumnIDs: []descpb.ColumnID{1},
+			Validity:            descpb.ConstraintValidity_Validated,
+		}}
+	})
+
+	manifests := map[string][]descpb.Descriptor{
+		"valid":       {*validTableDesc, *dbDesc, *droppedTableDesc},
+		"outside":     {*fkTableDesc, *dbDesc},
+		"unknown":     {*dbDesc, {}},
+		"no-database": {*validTableDesc},
+	}
+	defer func(reader func(context.Context, string) ([]descpb.Descriptor, hlc.Timestamp, error)) {
+		doctor.BackupManifestReader = reader
+	}(doctor.BackupManifestReader)
+	doctor.BackupManifestReader = func(
+		_ context.Context, manifestPath string,
+	) ([]descpb.Descriptor, hlc.Timestamp, error) {
+		descs, ok := manifests[manifestPath]
+		if !ok {
+			return nil, hlc.Timestamp{}, errors.New("no such manifest")
+		}
+		return descs, hlc.Timestamp{WallTime: 1}, nil
+	}
+
+	// Without a namespace table, no namespace entries are expected.
+	var buf bytes.Buffer
+	ok, err := doctor.ExamineBackup(ctx, "valid", &buf)
+	require.NoError(t, err)
+	require.True(t, ok, buf.String())
+	require.Equal(t, "Examining 3 descriptors of backup valid...\n", buf.String())
+
+	// References to descriptors outside the backup are only warnings.
+	buf.Reset()
+	ok, err = doctor.ExamineBackup(ctx, "outside", &buf)
+	require.NoError(t, err)
+	require.True(t, ok, buf.String())
+	require.Contains(t, buf.String(), `relation "t" (51): warning: `)
+	require.Contains(t, buf.String(), "referenced table ID 60")
+
+	// The parent database of a table may be outside the backup too.
+	buf.Reset()
+	ok, err = doctor.ExamineBackup(ctx, "no-database", &buf)
+	require.NoError(t, err)
+	require.True(t, ok, buf.String())
+
+	buf.Reset()
+	_, err = doctor.ExamineBackup(ctx, "unknown", &buf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unrecognized manifest format: descriptor at position 1 in unknown")
+
+	buf.Reset()
+	_, err = doctor.ExamineBackup(ctx, "missing", &buf)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to read backup manifest missing: no such manifest")
+}
@@ -0,0 +1,951 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catprivilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+)
+
+// checkDescriptor runs the doctor's own checks on desc, in addition to those
+// performed by descriptor validation. Unlike validation, these checks don't
+// stop at the first problem found. Those checks which would repeat the problem
+// validation stopped at are only run if desc is valid.
+func checkDescriptor(e *examination, desc catalog.Descriptor, valid bool) {
+	checkDescriptorName(e, desc)
+	switch desc := desc.(type) {
+	case catalog.TableDescriptor:
+		checkColumns(e, desc)
+		if valid {
+			checkIndexColumns(e, desc)
+		}
+		checkHiddenColumns(e, desc)
+		checkColumnFamilies(e, desc)
+		if valid {
+			checkPrimaryIndexCoverage(e, desc)
+		}
+		checkKeyNullability(e, desc)
+		checkPartitioning(e, desc)
+		checkColumnTypes(e, desc)
+		checkSequenceOptions(e, desc)
+		checkIDCounters(e, desc)
+		checkMutations(e, desc)
+		checkExpressions(e, desc)
+	case catalog.TypeDescriptor:
+		checkEnumMembers(e, desc)
+	}
+}
+
+// checkDescriptorName checks that desc, unless it's dropped, has a name usable
+// as an SQL identifier, if only when quoted: the name may not be empty or made
+// of whitespace only, and must be valid UTF-8 without control characters. Such
+// a name usually points to a corrupt descriptor. Descriptor validation only
+// rejects empty names, and stops at the first problem found.
+func checkDescriptorName(e *examination, desc catalog.Descriptor) {
+	if desc.Dropped() {
+		return
+	}
+	name := desc.GetName()
+	switch {
+	case name == "":
+		e.descReport(desc, InvalidName, "has an empty name")
+	case strings.TrimSpace(name) == "":
+		e.descReport(desc, InvalidName, "has a name made of whitespace only")
+	case !utf8.ValidString(name):
+		e.descReport(desc, InvalidName, "has a name which isn't valid UTF-8")
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		e.descReport(desc, InvalidName, "has a name with control characters")
+	}
+}
+
+// checkReferences runs the doctor's own checks on the references between desc
+// and other descriptors. Foreign keys are only checked if checkFKs is set, they
+// aren't when validation already reported on them.
+func checkReferences(
+	e *examination, ddg catalog.MapDescGetter, desc catalog.Descriptor, checkFKs bool,
+) {
+	switch desc := desc.(type) {
+	case catalog.TableDescriptor:
+		if checkFKs {
+			checkForeignKeys(e, ddg, desc)
+		}
+		checkDependencies(e, ddg, desc)
+		checkSequenceOwner(e, ddg, desc)
+		checkDroppedReferences(e, ddg, desc)
+		checkTypeReferences(e, ddg, desc)
+		checkDefaultExpressions(e, ddg, desc)
+		checkInterleaves(e, ddg, desc)
+		if !desc.IsTemporary() {
+			checkParentSchema(e, ddg, desc)
+		}
+	case catalog.TypeDescriptor:
+		checkTypeBackReferences(e, ddg, desc)
+		checkParentSchema(e, ddg, desc)
+	case catalog.SchemaDescriptor:
+		checkSchemaParent(e, ddg, desc)
+	case catalog.DatabaseDescriptor:
+		checkSchemaEntries(e, ddg, desc)
+	}
+	checkParentCycles(e, ddg, desc)
+}
+
+// checkNamespaceEntries checks that desc, unless it's dropped, is referenced by
+// at most one namespace entry besides those of its draining names. A missing
+// entry is reported by descriptor validation already, as are draining names
+// without an entry.
+func checkNamespaceEntries(e *examination, desc catalog.Descriptor, rows []NamespaceTableRow) {
+	if desc.Dropped() {
+		checkDroppedNamespaceEntries(e, desc, rows)
+		return
+	}
+	var names []string
+	for _, row := range rows {
+		if !isDrainingName(desc, row.NameInfo) {
+			names = append(names, fmt.Sprintf("(%d, %d, %s)", row.ParentID, row.ParentSchemaID, row.Name))
+		}
+	}
+	if len(names) > 1 {
+		e.descReport(desc, DuplicateNamespaceEntry,
+			"referenced by %d namespace entries: %s", len(names), strings.Join(names, ", "))
+	}
+}
+
+// checkDrainingNames checks that the draining names of desc which still have
+// their namespace entry are about to be drained by an unfinished schema change
+// job working on desc, given the IDs of the descriptors such jobs work on. A
+// draining name left behind by a stuck rename can't be reused. Draining names
+// without an entry are reported by descriptor validation already.
+func checkDrainingNames(
+	e *examination,
+	desc catalog.Descriptor,
+	rows []NamespaceTableRow,
+	schemaChanging map[descpb.ID]struct{},
+) {
+	if _, ok := schemaChanging[desc.GetID()]; ok {
+		return
+	}
+	for _, row := range rows {
+		if isDrainingName(desc, row.NameInfo) {
+			e.descReport(desc, StaleDrainingName,
+				"draining name (%d, %d, %s) still has its namespace entry, "+
+					"but no unfinished schema change job drains it",
+				row.ParentID, row.ParentSchemaID, row.Name)
+		}
+	}
+}
+
+// checkDroppedNamespaceEntries checks that a dropped descriptor isn't
+// referenced by any namespace entry besides those of its draining names: its
+// name is released when it's dropped, and only the names still draining, if
+// any, keep their entries until the drop is complete. Entries left behind
+// otherwise point to a drop which got stuck.
+func checkDroppedNamespaceEntries(
+	e *examination, desc catalog.Descriptor, rows []NamespaceTableRow,
+) {
+	for _, row := range rows {
+		if !isDrainingName(desc, row.NameInfo) {
+			e.descReport(desc, LingeringNamespaceEntry,
+				"descriptor %d in state %s still has namespace entry (%d, %d, %s), which should not exist",
+				desc.GetID(), descpb.DescriptorState_DROP, row.ParentID, row.ParentSchemaID, row.Name)
+		}
+	}
+}
+
+// checkReservedID checks that desc has an ID reserved for system descriptors
+// iff it is the system database or one of its descriptors. Other descriptors
+// with a reserved ID, as restored by mistake, collide with system descriptors.
+func checkReservedID(e *examination, desc catalog.Descriptor, idChecker keys.SystemIDChecker) {
+	_, isDatabase := desc.(catalog.DatabaseDescriptor)
+	isSystem := desc.GetParentID() == keys.SystemDatabaseID ||
+		(isDatabase && desc.GetID() == keys.SystemDatabaseID)
+	reserved := idChecker.IsSystemID(uint32(desc.GetID()))
+	switch {
+	case isSystem && !reserved:
+		e.descReport(desc, ReservedID,
+			"system descriptor ID %d is outside of the reserved IDs, which are below %d",
+			desc.GetID(), keys.MinUserDescriptorID(idChecker))
+	case !isSystem && reserved:
+		e.descReport(desc, ReservedID,
+			"user descriptor ID %d is reserved for system descriptors, user IDs start at %d",
+			desc.GetID(), keys.MinUserDescriptorID(idChecker))
+	}
+}
+
+// checkPrivileges checks the privileges of desc as stored in the descriptor
+// table, given its encoding. The privileges of desc itself can't be used, as
+// they have been fixed after deserialization, which hides any corruption.
+// Privileges older than 21.2 are expected to be fixed that way and are
+// skipped. Otherwise, the descriptor must have an owner, the superusers must
+// have exactly the privileges allowed to them, and every other user must have
+// some privileges, all of which are valid for the kind of descriptor.
+func checkPrivileges(e *examination, desc catalog.Descriptor, descBytes []byte) {
+	var d descpb.Descriptor
+	if err := protoutil.Unmarshal(descBytes, &d); err != nil {
+		// The descriptor was unmarshaled successfully already.
+		return
+	}
+	var privs *descpb.PrivilegeDescriptor
+	var objectType privilege.ObjectType
+	switch u := d.Union.(type) {
+	case *descpb.Descriptor_Table:
+		privs, objectType = u.Table.Privileges, privilege.Table
+	case *descpb.Descriptor_Database:
+		privs, objectType = u.Database.Privileges, privilege.Database
+	case *descpb.Descriptor_Schema:
+		privs, objectType = u.Schema.Privileges, privilege.Schema
+	case *descpb.Descriptor_Type:
+		privs, objectType = u.Type.Privileges, privilege.Type
+	}
+	if privs == nil || privs.Version < descpb.Version21_2 {
+		return
+	}
+	if privs.Owner().Undefined() {
+		e.descReport(desc, InvalidPrivilege, "has no owner")
+	}
+	superuserPrivs := catprivilege.SystemSuperuserPrivileges(desc)
+	if superuserPrivs == nil {
+		superuserPrivs = descpb.DefaultSuperuserPrivileges
+	}
+	for _, user := range []security.SQLUsername{security.RootUserName(), security.AdminRoleName()} {
+		if _, ok := privs.FindUser(user); !ok {
+			e.descReport(desc, InvalidPrivilege, "superuser %s has no privileges", user)
+		}
+	}
+	for _, u := range privs.Users {
+		user := u.User()
+		switch {
+		case u.Privileges == 0 && u.WithGrantOption == 0:
+			e.descReport(desc, InvalidPrivilege, "user %s has no privileges", user)
+		case user.IsRootUser() || user.IsAdminRole():
+			if u.Privileges != superuserPrivs.ToBitField() {
+				e.descReport(desc, InvalidPrivilege,
+					"superuser %s has privileges %s, expected exactly %s",
+					user, privilegeString(u.Privileges), superuserPrivs)
+			}
+		default:
+			userPrivs := descpb.PrivilegeDescriptor{Users: []descpb.UserPrivileges{u}, Version: privs.Version}
+			if ok, _, invalid := userPrivs.IsValidPrivilegesForObjectType(objectType); !ok {
+				e.descReport(desc, InvalidPrivilege,
+					"user %s has privileges %s, which are invalid for a %s",
+					user, privilegeString(invalid), objectType)
+			}
+		}
+	}
+}
+
+// checkStoredTable runs the checks which need a table as stored, before
+// deserialization upgrades its format version and the version of its primary
+// index, which hides any corruption of these.
+func checkStoredTable(e *examination, desc catalog.Descriptor, descBytes []byte) {
+	if _, ok := desc.(catalog.TableDescriptor); !ok {
+		return
+	}
+	var d descpb.Descriptor
+	if err := protoutil.Unmarshal(descBytes, &d); err != nil {
+		// The descriptor was unmarshaled successfully already.
+		return
+	}
+	tbl, _, _, _ := descpb.FromDescriptor(&d)
+	if tbl == nil {
+		return
+	}
+	checkFormatVersion(e, desc, tbl)
+	checkIndexVersions(e, desc, tbl)
+}
+
+// checkFormatVersion checks that the stored table tbl is consistent with its
+// format version. A format version newer than the latest one may come with an
+// encoding this binary can't read. A table claiming a format version which
+// predates column families but having some is the remains of an interrupted
+// upgrade: its families are replaced with generated ones when it's read. The
+// same goes for interleaved indexes in a table whose format version predates
+// interleaved tables.
+func checkFormatVersion(e *examination, desc catalog.Descriptor, tbl *descpb.TableDescriptor) {
+	const latest = descpb.InterleavedFormatVersion
+	if tbl.FormatVersion > latest {
+		e.descReport(desc, InvalidFormatVersion,
+			"has format version %d, but the latest format version supported is %d",
+			tbl.FormatVersion, latest)
+		return
+	}
+	if tbl.FormatVersion < descpb.FamilyFormatVersion && len(tbl.Families) > 0 {
+		e.descReport(desc, InvalidFormatVersion,
+			"has format version %d, which predates column families, but has column families",
+			tbl.FormatVersion)
+	}
+	if tbl.FormatVersion < descpb.InterleavedFormatVersion {
+		indexes := []*descpb.IndexDescriptor{&tbl.PrimaryIndex}
+		for i := range tbl.Indexes {
+			indexes = append(indexes, &tbl.Indexes[i])
+		}
+		for _, m := range tbl.Mutations {
+			if idx := m.GetIndex(); idx != nil {
+				indexes = append(indexes, idx)
+			}
+		}
+		for _, idx := range indexes {
+			if len(idx.Interleave.Ancestors) > 0 || len(idx.InterleavedBy) > 0 {
+				e.descReport(desc, InvalidFormatVersion,
+					"has format version %d, which predates interleaved tables, but index %q is interleaved",
+					tbl.FormatVersion, idx.Name)
+			}
+		}
+	}
+}
+
+// checkIndexVersions checks that the indexes of the stored table tbl have a
+// version which this binary supports, as a newer one may have an encoding it
+// can't read. Older versions are upgraded when read where possible; those of
+// indexes which are left behind, such as inverted indexes lacking the encoding
+// of empty arrays, are obsolete and warned about, as only rebuilding the index
+// upgrades them. Indexes added by mutations are checked as primary or
+// secondary according to their encoding.
+func checkIndexVersions(e *examination, desc catalog.Descriptor, tbl *descpb.TableDescriptor) {
+	table, ok := desc.(catalog.TableDescriptor)
+	if !ok {
+		return
+	}
+	check := func(idx *descpb.IndexDescriptor, primary bool) {
+		kind, latest := "secondary", descpb.LatestNonPrimaryIndexDescriptorVersion
+		if primary {
+			kind, latest = "primary", descpb.LatestPrimaryIndexDescriptorVersion
+		}
+		if idx.Version > latest {
+			e.descReport(desc, InvalidIndexVersion,
+				"%s index %q has version %d, but the latest version supported for %s indexes is %d",
+				kind, idx.Name, idx.Version, kind, latest)
+			return
+		}
+		if upgraded, err := table.FindIndexWithID(idx.ID); err == nil && upgraded.GetVersion() < latest {
+			e.descReport(desc, ObsoleteIndexVersion,
+				"%s index %q has obsolete version %d, which can't be upgraded to %d without rebuilding it",
+				kind, idx.Name, upgraded.GetVersion(), latest)
+		}
+	}
+	if tbl.IsPhysicalTable() {
+		check(&tbl.PrimaryIndex, true /* primary */)
+	}
+	for i := range tbl.Indexes {
+		check(&tbl.Indexes[i], false /* primary */)
+	}
+	for _, m := range tbl.Mutations {
+		if idx := m.GetIndex(); idx != nil {
+			check(idx, idx.EncodingType == descpb.PrimaryIndexEncoding)
+		}
+	}
+}
+
+// checkMutations checks that the mutations of a table are in non-decreasing
+// order of mutation ID, that they have a direction, and that the column or
+// index each of them adds or drops is neither public nor the subject of
+// another mutation. Mutation IDs beyond NextMutationID are reported by
+// checkIDCounters.
+func checkMutations(e *examination, table catalog.TableDescriptor) {
+	publicColumns := make(map[descpb.ColumnID]struct{})
+	for _, col := range table.PublicColumns() {
+		publicColumns[col.GetID()] = struct{}{}
+	}
+	publicIndexes := make(map[descpb.IndexID]struct{})
+	for _, idx := range table.ActiveIndexes() {
+		publicIndexes[idx.GetID()] = struct{}{}
+	}
+	mutatedColumns := make(map[descpb.ColumnID]int)
+	mutatedIndexes := make(map[descpb.IndexID]int)
+	mutations := table.TableDesc().Mutations
+	for i := range mutations {
+		m := &mutations[i]
+		if i > 0 && m.MutationID < mutations[i-1].MutationID {
+			e.descReport(table, InvalidMutation,
+				"mutation %d has mutation ID %d, which is lower than mutation ID %d of mutation %d",
+				i, m.MutationID, mutations[i-1].MutationID, i-1)
+		}
+		var verb, public string
+		switch m.Direction {
+		case descpb.DescriptorMutation_ADD:
+			verb, public = "adds", "is already public"
+		case descpb.DescriptorMutation_DROP:
+			verb, public = "drops", "is still public"
+		default:
+			e.descReport(table, InvalidMutation,
+				"mutation %d in state %s has no direction", i, m.State)
+			continue
+		}
+		if col := m.GetColumn(); col != nil {
+			if _, ok := publicColumns[col.ID]; ok {
+				e.descReport(table, InvalidMutation,
+					"mutation %d %s column %q (%d), which %s", i, verb, col.Name, col.ID, public)
+			}
+			if other, ok := mutatedColumns[col.ID]; ok {
+				e.descReport(table, InvalidMutation,
+					"mutation %d %s column %q (%d), which mutation %d also refers to",
+					i, verb, col.Name, col.ID, other)
+			} else {
+				mutatedColumns[col.ID] = i
+			}
+		}
+		if idx := m.GetIndex(); idx != nil {
+			if _, ok := publicIndexes[idx.ID]; ok {
+				e.descReport(table, InvalidMutation,
+					"mutation %d %s index %q (%d), which %s", i, verb, idx.Name, idx.ID, public)
+			}
+			if other, ok := mutatedIndexes[idx.ID]; ok {
+				e.descReport(table, InvalidMutation,
+					"mutation %d %s index %q (%d), which mutation %d also refers to",
+					i, verb, idx.Name, idx.ID, other)
+			} else {
+				mutatedIndexes[idx.ID] = i
+			}
+		}
+	}
+}
+
+// checkVersion checks that desc, unless it's dropped, has a non-zero version
+// and a modification time which is set and not after now.
+func checkVersion(e *examination, desc catalog.Descriptor, now hlc.Timestamp) {
+	if desc.Dropped() {
+		return
+	}
+	if desc.GetVersion() == 0 {
+		e.descReport(desc, InvalidVersion, "has version 0")
+	}
+	switch modTime := desc.GetModificationTime(); {
+	case modTime.IsEmpty():
+		e.descReport(desc, InvalidVersion, "version %d has no modification time", desc.GetVersion())
+	case now.Less(modTime):
+		e.descReport(desc, InvalidVersion,
+			"version %d has modification time %s, which is after now (%s)",
+			desc.GetVersion(), modTime, now)
+	}
+}
+
+// privilegeString formats a privilege bit field, including any bits which
+// don't correspond to a known privilege.
+func privilegeString(bits uint32) string {
+	privs := privilege.ListFromBitField(bits, privilege.Any)
+	var parts []string
+	if len(privs) > 0 {
+		parts = append(parts, privs.String())
+	}
+	if unknown := bits &^ privs.ToBitField(); unknown != 0 {
+		parts = append(parts, fmt.Sprintf("unknown bits %#x", unknown))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// isDrainingName returns whether ni is one of the draining names of desc.
+func isDrainingName(desc catalog.Descriptor, ni descpb.NameInfo) bool {
+	for _, dn := range desc.GetDrainingNames() {
+		if dn == ni {
+			return true
+		}
+	}
+	return false
+}
+
+// columnsByID returns the table's columns, including those in mutations,
+// indexed by column ID.
+func columnsByID(table catalog.TableDescriptor) map[descpb.ColumnID]catalog.Column {
+	columns := make(map[descpb.ColumnID]catalog.Column, len(table.DeletableColumns()))
+	for _, col := range table.DeletableColumns() {
+		columns[col.GetID()] = col
+	}
+	return columns
+}
+
+// checkColumns checks that the names and IDs of the table's columns, including
+// those in mutations, are unique within the table. Stored index columns are
+// checked against them by checkIndexColumns, which looks the columns up by ID.
+func checkColumns(e *examination, table catalog.TableDescriptor) {
+	names := make(map[string]catalog.Column)
+	ids := make(map[descpb.ColumnID]catalog.Column)
+	for _, col := range table.DeletableColumns() {
+		if other, ok := names[col.GetName()]; ok {
+			e.descReport(table, DuplicateColumn,
+				"column %q (%d) has the same name as column %d", col.GetName(), col.GetID(), other.GetID())
+		} else {
+			names[col.GetName()] = col
+		}
+		if other, ok := ids[col.GetID()]; ok {
+			e.descReport(table, DuplicateColumn,
+				"column %q (%d) has the same ID as column %q", col.GetName(), col.GetID(), other.GetName())
+		} else {
+			ids[col.GetID()] = col
+		}
+	}
+}
+
+// checkIndexColumns checks that every stored column ID in each of the table's
+// indexes refers to an existing column, and that the stored column names of
+// each index are consistent with its stored column IDs. Descriptor validation
+// checks the key and key suffix columns, but not the stored ones, and it only
+// checks the number of stored column names of the indexes not being dropped.
+// Indexes in the old STORING encoding keep the IDs of their stored columns
+// among their key suffix columns, so their stored column names aren't
+// compared to their stored column IDs.
+func checkIndexColumns(e *examination, table catalog.TableDescriptor) {
+	columns := columnsByID(table)
+	for _, idx := range table.AllIndexes() {
+		idxDesc := idx.IndexDesc()
+		oldStoredColumns := idx.HasOldStoredColumns()
+		if len(idxDesc.StoreColumnIDs) > len(idxDesc.StoreColumnNames) {
+			e.descReport(table, InvalidIndexColumn,
+				"index %q has %d stored column IDs but only %d stored column names",
+				idx.GetName(), len(idxDesc.StoreColumnIDs), len(idxDesc.StoreColumnNames))
+		}
+		for i, id := range idxDesc.StoreColumnIDs {
+			col, ok := columns[id]
+			if !ok {
+				e.descReport(table, InvalidIndexColumn,
+					"index %q references missing column ID %d", idx.GetName(), id)
+				continue
+			}
+			if !oldStoredColumns && i < len(idxDesc.StoreColumnNames) &&
+				idxDesc.StoreColumnNames[i] != col.GetName() {
+				e.descReport(table, InvalidIndexColumn,
+					"index %q stored column ID %d at position %d has name %q, expected %q",
+					idx.GetName(), id, i, idxDesc.StoreColumnNames[i], col.GetName())
+			}
+		}
+	}
+}
+
+// checkPrimaryIndexCoverage checks that the primary index of a table stores
+// every non-virtual public column, either as a key column or as a stored
+// column, and that it's consistent with the family layout: the columns of the
+// families, which make up the value of each row, must be key or stored columns
+// too. This includes columns being added, which are in a family and stored
+// from the start. Primary indexes older than
+// PrimaryIndexWithStoredColumnsVersion don't list their stored columns, as they
+// implicitly store every column.
+func checkPrimaryIndexCoverage(e *examination, table catalog.TableDescriptor) {
+	if !table.IsTable() {
+		return
+	}
+	primary := table.GetPrimaryIndex()
+	if primary.GetVersion() < descpb.PrimaryIndexWithStoredColumnsVersion {
+		return
+	}
+	covered := catalog.MakeTableColSet(primary.IndexDesc().KeyColumnIDs...)
+	covered.UnionWith(catalog.MakeTableColSet(primary.IndexDesc().StoreColumnIDs...))
+	var uncovered catalog.TableColSet
+	for _, col := range table.PublicColumns() {
+		if !col.IsVirtual() && !covered.Contains(col.GetID()) {
+			uncovered.Add(col.GetID())
+		}
+	}
+	// Family columns which don't exist or are virtual are reported along with
+	// the families.
+	families := table.GetFamilies()
+	for i := range families {
+		for _, id := range families[i].ColumnIDs {
+			if col, err := table.FindColumnWithID(id); err == nil && !col.IsVirtual() && !covered.Contains(id) {
+				uncovered.Add(id)
+			}
+		}
+	}
+	var missing []string
+	uncovered.ForEach(func(id descpb.ColumnID) {
+		if col, err := table.FindColumnWithID(id); err == nil {
+			missing = append(missing, fmt.Sprintf("%q (%d)", col.GetName(), id))
+		}
+	})
+	if len(missing) > 0 {
+		e.descReport(table, IncompletePrimaryIndex,
+			"primary index %q doesn't store %s %s",
+			primary.GetName(), pluralize(len(missing), "column"), strings.Join(missing, ", "))
+	}
+}
+
+// checkKeyNullability checks that the key columns of the primary index of a
+// table aren't nullable. The primary index is unique and its encoding assumes
+// that none of its key columns is NULL. Unique secondary indexes may have
+// nullable key columns, as they tell NULLs apart by adding the primary key
+// columns to their key as a suffix, so those must be in their key suffix
+// columns: without them, rows with the same NULL key would collide. Missing
+// key columns are reported by descriptor validation.
+func checkKeyNullability(e *examination, table catalog.TableDescriptor) {
+	if !table.IsTable() {
+		return
+	}
+	primary := table.GetPrimaryIndex()
+	for _, id := range primary.IndexDesc().KeyColumnIDs {
+		col, err := table.FindColumnWithID(id)
+		if err == nil && col.IsNullable() {
+			e.descReport(table, NullableKeyColumn,
+				"primary index %q key column %q (%d) is nullable", primary.GetName(), col.GetName(), id)
+		}
+	}
+	for _, idx := range table.DeletableNonPrimaryIndexes() {
+		if !idx.IsUnique() {
+			continue
+		}
+		keyColumns := catalog.MakeTableColSet(idx.IndexDesc().KeyColumnIDs...)
+		suffixColumns := catalog.MakeTableColSet(idx.IndexDesc().KeySuffixColumnIDs...)
+		var missing []string
+		for i := 0; i < primary.NumKeyColumns(); i++ {
+			if id := primary.GetKeyColumnID(i); !keyColumns.Contains(id) && !suffixColumns.Contains(id) {
+				missing = append(missing, fmt.Sprintf("%q (%d)", primary.GetKeyColumnName(i), id))
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		for _, id := range idx.IndexDesc().KeyColumnIDs {
+			col, err := table.FindColumnWithID(id)
+			if err == nil && col.IsNullable() {
+				e.descReport(table, NullableKeyColumn,
+					"unique index %q key column %q (%d) is nullable, but the index lacks key suffix %s %s",
+					idx.GetName(), col.GetName(), id,
+					pluralize(len(missing), "column"), strings.Join(missing, ", "))
+			}
+		}
+	}
+}
+
+// checkHiddenColumns checks that a table's implicit row ID column, which a
+// table without an explicit primary key uses as its key, is hidden. Such a
+// column can't be told apart for sure from a user-declared primary key column
+// of the same name, type and default, so a visible one is only a warning, and
+// only when it is the sole key column of the primary index.
+func checkHiddenColumns(e *examination, table catalog.TableDescriptor) {
+	if !table.IsTable() {
+		return
+	}
+	primary := table.GetPrimaryIndex()
+	if primary.NumKeyColumns() != 1 {
+		return
+	}
+	col, err := table.FindColumnWithID(primary.GetKeyColumnID(0))
+	if err != nil || col.IsHidden() {
+		return
+	}
+	if strings.HasPrefix(col.GetName(), "rowid") && col.GetType().Equal(types.Int) &&
+		col.GetDefaultExpr() == "unique_rowid()" {
+		e.descReport(table, InvalidHiddenColumn,
+			"column %q (%d) looks like the implicit row ID column of primary index %q but isn't hidden",
+			col.GetName(), col.GetID(), primary.GetName())
+	}
+}
+
+// checkColumnFamilies checks that the column families of a physical table are
+// consistent with its columns: every column ID in a family must refer to an
+// existing, non-virtual column with the same name, every non-virtual column
+// must be in exactly one family, and the default column of each family, if
+// any, must be in that family. Virtual columns are computed when read, and have
+// no value stored in any family. No family other than the primary one may have a primary key
+// column as its default column: the primary key columns are encoded in the key
+// rather than in the families, so such a family, whose value is only its
+// default column, would be empty. Descriptor validation stops at the first
+// problem with the families, this reports them all.
+func checkColumnFamilies(e *examination, table catalog.TableDescriptor) {
+	if !table.IsPhysicalTable() {
+		return
+	}
+	columns := columnsByID(table)
+	familyOf := make(map[descpb.ColumnID]*descpb.ColumnFamilyDescriptor, len(columns))
+	families := table.GetFamilies()
+	keyColumns := catalog.MakeTableColSet(table.GetPrimaryIndex().IndexDesc().KeyColumnIDs...)
+	for i := range families {
+		family := &families[i]
+		if len(family.ColumnIDs) != len(family.ColumnNames) {
+			e.descReport(table, InvalidColumnFamily,
+				"family %q (%d) has %d column IDs but %d column names",
+				family.Name, family.ID, len(family.ColumnIDs), len(family.ColumnNames))
+		}
+		for j, id := range family.ColumnIDs {
+			col, ok := columns[id]
+			if !ok {
+				e.descReport(table, InvalidColumnFamily,
+					"family %q (%d) references missing column ID %d", family.Name, family.ID, id)
+				continue
+			}
+			if j < len(family.ColumnNames) && family.ColumnNames[j] != col.GetName() {
+				e.descReport(table, InvalidColumnFamily,
+					"family %q (%d) column ID %d has name %q, expected %q",
+					family.Name, family.ID, id, family.ColumnNames[j], col.GetName())
+			}
+			if col.IsVirtual() {
+				e.descReport(table, InvalidColumnFamily,
+					"family %q (%d) contains virtual column %q (%d)", family.Name, family.ID, col.GetName(), id)
+			}
+			if other, ok := familyOf[id]; ok {
+				e.descReport(table, InvalidColumnFamily,
+					"family %q (%d) contains column %q (%d) which is also in family %q (%d)",
+					family.Name, family.ID, col.GetName(), id, other.Name, other.ID)
+				continue
+			}
+			familyOf[id] = family
+		}
+		if family.DefaultColumnID == 0 {
+			continue
+		}
+		inFamily := false
+		for _, id := range family.ColumnIDs {
+			inFamily = inFamily || id == family.DefaultColumnID
+		}
+		if !inFamily {
+			e.descReport(table, InvalidColumnFamily,
+				"family %q (%d) has default column ID %d which is not in the family",
+				family.Name, family.ID, family.DefaultColumnID)
+			continue
+		}
+		if family.ID == 0 || !keyColumns.Contains(family.DefaultColumnID) {
+			continue
+		}
+		name := ""
+		if col, ok := columns[family.DefaultColumnID]; ok {
+			name = col.GetName()
+		}
+		e.descReport(table, InvalidColumnFamily,
+			"family %q (%d) has primary key column %q (%d) as its default column",
+			family.Name, family.ID, name, family.DefaultColumnID)
+	}
+	for _, col := range table.DeletableColumns() {
+		if _, ok := familyOf[col.GetID()]; !ok && !col.IsVirtual() {
+			e.descReport(table, InvalidColumnFamily,
+				"column %q (%d) is not in any family", col.GetName(), col.GetID())
+		}
+	}
+}
+
+// checkPartitioning checks that the partitioning of each of the table's
+// indexes, including subpartitioning, only uses existing key columns of the
+// index, and that the partition names are unique within the index.
+func checkPartitioning(e *examination, table catalog.TableDescriptor) {
+	columns := columnsByID(table)
+	for _, idx := range table.AllIndexes() {
+		names := make(map[string]struct{})
+		checkPartitioningDesc(e, table, idx, columns, &idx.IndexDesc().Partitioning, 0, "", names)
+	}
+}
+
+// checkPartitioningDesc checks part, which partitions idx by the key columns
+// following the first offset ones. If part is a subpartitioning, partition
+// is the name of the partition it belongs to. names collects the names of the
+// partitions of idx checked so far.
+func checkPartitioningDesc(
+	e *examination,
+	table catalog.TableDescriptor,
+	idx catalog.Index,
+	columns map[descpb.ColumnID]catalog.Column,
+	part *descpb.PartitioningDescriptor,
+	offset int,
+	partition string,
+	names map[string]struct{},
+) {
+	if part.NumColumns == 0 {
+		return
+	}
+	of := fmt.Sprintf("index %q", idx.GetName())
+	if partition != "" {
+		of = fmt.Sprintf("partition %q of %s", partition, of)
+	}
+	keyColumnIDs := idx.IndexDesc().KeyColumnIDs
+	end := offset + int(part.NumColumns)
+	if end > len(keyColumnIDs) {
+		e.descReport(table, InvalidPartitioning,
+			"partitioning of %s needs %d key columns, but the index has %d",
+			of, end, len(keyColumnIDs))
+	} else {
+		for _, id := range keyColumnIDs[offset:end] {
+			if _, ok := columns[id]; !ok {
+				e.descReport(table, InvalidPartitioning,
+					"partitioning of %s uses missing column ID %d", of, id)
+			}
+		}
+	}
+	checkName := func(name string) {
+		if _, ok := names[name]; ok {
+			e.descReport(table, InvalidPartitioning,
+				"index %q has more than one partition named %q", idx.GetName(), name)
+		}
+		names[name] = struct{}{}
+	}
+	for i := range part.List {
+		l := &part.List[i]
+		checkName(l.Name)
+		checkPartitioningDesc(e, table, idx, columns, &l.Subpartitioning, end, l.Name, names)
+	}
+	for i := range part.Range {
+		checkName(part.Range[i].Name)
+	}
+}
+
+// checkSequenceOptions checks that the options of a sequence allow nextval to
+// advance it: its increment may not be zero, its minimum value may not be
+// greater than its maximum value, its start value must be between them, and
+// its cache size may not be negative. A cache size of zero, as found in
+// sequences created before caching was supported, means no caching. Invalid
+// options only show when the sequence is next advanced.
+func checkSequenceOptions(e *examination, table catalog.TableDescriptor) {
+	if !table.IsSequence() {
+		return
+	}
+	opts := table.GetSequenceOpts()
+	if opts.Increment == 0 {
+		e.descReport(table, InvalidSequenceOptions, "has an increment of zero")
+	}
+	if opts.MinValue > opts.MaxValue {
+		e.descReport(table, InvalidSequenceOptions,
+			"has a minimum value %d greater than its maximum value %d", opts.MinValue, opts.MaxValue)
+	} else if opts.Start < opts.MinValue || opts.Start > opts.MaxValue {
+		e.descReport(table, InvalidSequenceOptions,
+			"has a start value %d outside of its range [%d, %d]", opts.Start, opts.MinValue, opts.MaxValue)
+	}
+	if opts.CacheSize < 0 {
+		e.descReport(table, InvalidSequenceOptions, "has a negative cache size %d", opts.CacheSize)
+	}
+}
+
+// checkColumnTypes checks that every column of the table has a well-formed
+// type, which would otherwise cause panics once the column is used.
+func checkColumnTypes(e *examination, table catalog.TableDescriptor) {
+	for _, col := range table.DeletableColumns() {
+		if problem := typeProblem(col.GetType()); problem != "" {
+			e.descReport(table, InvalidColumnType,
+				"column %q (%d) has an invalid type: %s", col.GetName(), col.GetID(), problem)
+		}
+	}
+}
+
+// typeProblem describes what's wrong with a column type, or returns the empty
+// string if nothing is. The type must be set, be of a known family other than
+// those only used for expressions, and have a known OID unless it's
+// user-defined. The element types of arrays and tuples must be valid too.
+func typeProblem(t *types.T) string {
+	if t == nil {
+		return "no type"
+	}
+	family := t.Family()
+	if _, ok := types.Family_name[int32(family)]; !ok {
+		return fmt.Sprintf("unknown family %d (OID %d)", int32(family), t.Oid())
+	}
+	switch family {
+	case types.UnknownFamily, types.AnyFamily, types.VoidFamily:
+		return fmt.Sprintf("family %s (OID %d) is not a column type", family, t.Oid())
+	}
+	if !types.IsOIDUserDefinedType(t.Oid()) {
+		if _, ok := types.OidToType[t.Oid()]; !ok {
+			return fmt.Sprintf("unknown OID %d of family %s", t.Oid(), family)
+		}
+	}
+	switch family {
+	case types.ArrayFamily:
+		if problem := typeProblem(t.ArrayContents()); problem != "" {
+			return "array element type: " + problem
+		}
+	case types.TupleFamily:
+		for i, contents := range t.TupleContents() {
+			if problem := typeProblem(contents); problem != "" {
+				return fmt.Sprintf("tuple element %d type: %s", i, problem)
+			}
+		}
+	}
+	return ""
+}
+
+// checkEnumMembers checks that the members of an enum have non-empty, unique
+// names and physical representations, and that they're ordered by their
+// physical representations, which is the order of the encoded values.
+func checkEnumMembers(e *examination, typ catalog.TypeDescriptor) {
+	members := typ.TypeDesc().EnumMembers
+	names := make(map[string]int, len(members))
+	physicalReps := make(map[string]int, len(members))
+	prev := -1
+	for i := range members {
+		member := &members[i]
+		if member.LogicalRepresentation == "" {
+			e.descReport(typ, InvalidEnumMember, "enum member %d has an empty name", i)
+		} else if other, ok := names[member.LogicalRepresentation]; ok {
+			e.descReport(typ, InvalidEnumMember,
+				"enum member %d (%q) has the same name as member %d", i, member.LogicalRepresentation, other)
+		} else {
+			names[member.LogicalRepresentation] = i
+		}
+		rep := member.PhysicalRepresentation
+		if len(rep) == 0 {
+			e.descReport(typ, InvalidEnumMember,
+				"enum member %d (%q) has an empty physical representation", i, member.LogicalRepresentation)
+			continue
+		}
+		if other, ok := physicalReps[string(rep)]; ok {
+			e.descReport(typ, InvalidEnumMember,
+				"enum member %d (%q) has the same physical representation %x as member %d (%q)",
+				i, member.LogicalRepresentation, rep, other, members[other].LogicalRepresentation)
+		} else {
+			physicalReps[string(rep)] = i
+			if prev >= 0 && bytes.Compare(rep, members[prev].PhysicalRepresentation) <= 0 {
+				e.descReport(typ, InvalidEnumMember,
+					"enum member %d (%q) has physical representation %x, "+
+						"which isn't greater than %x of member %d (%q)",
+					i, member.LogicalRepresentation, rep, members[prev].PhysicalRepresentation,
+					prev, members[prev].LogicalRepresentation)
+			}
+		}
+		prev = i
+	}
+}
+
+// checkIDCounters checks that the table's Next* counters are greater than every
+// ID allocated with them, as otherwise future allocations may reuse an ID.
+func checkIDCounters(e *examination, table catalog.TableDescriptor) {
+	if table.IsSequence() || table.Dropped() {
+		// Neither sequences nor dropped tables allocate IDs anymore, and some
+		// system sequences leave their counters unset.
+		return
+	}
+	tbl := table.TableDesc()
+	for _, col := range table.DeletableColumns() {
+		if col.GetID() >= tbl.NextColumnID {
+			e.descReport(table, StaleIDCounter,
+				"NextColumnID %d is not greater than ID %d of column %q",
+				tbl.NextColumnID, col.GetID(), col.GetName())
+		}
+	}
+	for _, family := range tbl.Families {
+		if family.ID >= tbl.NextFamilyID {
+			e.descReport(table, StaleIDCounter,
+				"NextFamilyID %d is not greater than ID %d of family %q",
+				tbl.NextFamilyID, family.ID, family.Name)
+		}
+	}
+	// Only physical tables have indexes, views have an empty primary index.
+	if table.IsPhysicalTable() {
+		for _, idx := range table.AllIndexes() {
+			// A primary index with ID 0 is missing, which validation reports.
+			if idx.GetID() != 0 && idx.GetID() >= tbl.NextIndexID {
+				e.descReport(table, StaleIDCounter,
+					"NextIndexID %d is not greater than ID %d of index %q",
+					tbl.NextIndexID, idx.GetID(), idx.GetName())
+			}
+		}
+	}
+	for _, m := range tbl.Mutations {
+		if m.MutationID >= tbl.NextMutationID {
+			e.descReport(table, StaleIDCounter,
+				"NextMutationID %d is not greater than ID %d of mutation in state %s",
+				tbl.NextMutationID, m.MutationID, m.State)
+		}
+	}
+}
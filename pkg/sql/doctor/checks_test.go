@@ -0,0 +1,962 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor_test
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/security"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
+	"github.com/cockroachdb/cockroach/pkg/sql/privilege"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDescriptorName(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "names",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = ""
+				}))},
+				dbRow(t),
+				{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = " \t"
+					tbl.ID = 53
+				}))},
+				{ID: 54, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "a\x00b"
+					tbl.ID = 54
+				}))},
+				// A dropped descriptor's name doesn't matter anymore.
+				{ID: 55, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = ""
+					tbl.ID = 55
+					tbl.State = descpb.DescriptorState_DROP
+				}))},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("", 51), dbNamespaceRow, tableNamespaceRow(" \t", 53), tableNamespaceRow("a\x00b", 54),
+			},
+			expected: []string{
+				`invalid name: relation "" (51): has an empty name`,
+				`invalid name: relation " \t" (53): has a name made of whitespace only`,
+				`invalid name: relation "a\x00b" (54): has a name with control characters`,
+			},
+		},
+	})
+}
+
+func TestCheckColumns(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "duplicate columns",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Columns = append(tbl.Columns,
+						descpb.ColumnDescriptor{Name: "col", ID: 2, Type: types.Int, Nullable: true},
+						descpb.ColumnDescriptor{Name: "b", ID: 3, Type: types.Int, Nullable: true},
+						descpb.ColumnDescriptor{Name: "c", ID: 3, Type: types.Int, Nullable: true},
+					)
+					tbl.NextColumnID = 4
+					tbl.Families[0].ColumnNames = []string{"col", "col", "c"}
+					tbl.Families[0].ColumnIDs = []descpb.ColumnID{1, 2, 3}
+					tbl.PrimaryIndex.StoreColumnNames = []string{"col", "c"}
+					tbl.PrimaryIndex.StoreColumnIDs = []descpb.ColumnID{2, 3}
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`duplicate column: relation "t" (51): column "col" (2) has the same name as column 1`,
+				`duplicate column: relation "t" (51): column "c" (3) has the same ID as column "b"`,
+			},
+		},
+	})
+}
+
+func TestCheckIndexColumns(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "index columns",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Indexes = []descpb.IndexDescriptor{{
+						Name:                "idx",
+						ID:                  2,
+						KeyColumnNames:      []string{"col"},
+						KeyColumnDirections: []descpb.IndexDescriptor_Direction{descpb.IndexDescriptor_ASC},
+						KeyColumnIDs:        []descpb.ColumnID{1},
+						StoreColumnNames:    []string{"dropped", "gone"},
+						StoreColumnIDs:      []descpb.ColumnID{5, 6},
+						Version:             descpb.StrictIndexColumnIDGuaranteesVersion,
+					}}
+					tbl.NextIndexID = 3
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`index column: relation "t" (51): index "idx" references missing column ID 5`,
+				`index column: relation "t" (51): index "idx" references missing column ID 6`,
+			},
+		},
+		{
+			name: "index stored column names",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					for _, col := range []descpb.ColumnDescriptor{
+						{Name: "a", ID: 2, Type: types.Int, Nullable: true},
+						{Name: "b", ID: 3, Type: types.Int, Nullable: true},
+					} {
+						tbl.Columns = append(tbl.Columns, col)
+						tbl.Families[0].ColumnNames = append(tbl.Families[0].ColumnNames, col.Name)
+						tbl.Families[0].ColumnIDs = append(tbl.Families[0].ColumnIDs, col.ID)
+					}
+					tbl.NextColumnID = 4
+					tbl.PrimaryIndex.StoreColumnNames = []string{"b", "a", "c"}
+					tbl.PrimaryIndex.StoreColumnIDs = []descpb.ColumnID{2, 3}
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`index column: relation "t" (51): index "t_pkey" stored column ID 2 at position 0 has name "b", expected "a"`,
+				`index column: relation "t" (51): index "t_pkey" stored column ID 3 at position 1 has name "a", expected "b"`,
+			},
+		},
+		{
+			// Validation doesn't check the indexes being dropped.
+			name: "index stored column count",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Columns = append(tbl.Columns, descpb.ColumnDescriptor{
+						Name: "b", ID: 2, Type: types.Int, Nullable: true,
+					})
+					tbl.NextColumnID = 3
+					tbl.Families[0].ColumnNames = []string{"col", "b"}
+					tbl.Families[0].ColumnIDs = []descpb.ColumnID{1, 2}
+					tbl.PrimaryIndex.StoreColumnNames = []string{"b"}
+					tbl.PrimaryIndex.StoreColumnIDs = []descpb.ColumnID{2}
+					tbl.Mutations = []descpb.DescriptorMutation{{
+						Descriptor_: &descpb.DescriptorMutation_Index{Index: &descpb.IndexDescriptor{
+							Name:                "idx",
+							ID:                  2,
+							KeyColumnNames:      []string{"col"},
+							KeyColumnDirections: []descpb.IndexDescriptor_Direction{descpb.IndexDescriptor_ASC},
+							KeyColumnIDs:        []descpb.ColumnID{1},
+							StoreColumnIDs:      []descpb.ColumnID{2},
+							Version:             descpb.StrictIndexColumnIDGuaranteesVersion,
+						}},
+						State:      descpb.DescriptorMutation_DELETE_ONLY,
+						Direction:  descpb.DescriptorMutation_DROP,
+						MutationID: 1,
+					}}
+					tbl.NextIndexID = 3
+					tbl.NextMutationID = 2
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`index column: relation "t" (51): index "idx" has 1 stored column IDs but only 0 stored column names`,
+			},
+		},
+		{
+			// In the old STORING encoding, the stored column IDs are key suffix
+			// column IDs, which leaves more stored column names than IDs.
+			name: "old STORING encoding",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					for _, col := range []descpb.ColumnDescriptor{
+						{Name: "b", ID: 2, Type: types.Int, Nullable: true},
+						{Name: "c", ID: 3, Type: types.Int, Nullable: true},
+					} {
+						tbl.Columns = append(tbl.Columns, col)
+						tbl.Families[0].ColumnNames = append(tbl.Families[0].ColumnNames, col.Name)
+						tbl.Families[0].ColumnIDs = append(tbl.Families[0].ColumnIDs, col.ID)
+						tbl.PrimaryIndex.StoreColumnNames = append(tbl.PrimaryIndex.StoreColumnNames, col.Name)
+						tbl.PrimaryIndex.StoreColumnIDs = append(tbl.PrimaryIndex.StoreColumnIDs, col.ID)
+					}
+					tbl.NextColumnID = 4
+					tbl.Indexes = []descpb.IndexDescriptor{{
+						Name:                "idx",
+						ID:                  2,
+						KeyColumnNames:      []string{"b"},
+						KeyColumnDirections: []descpb.IndexDescriptor_Direction{descpb.IndexDescriptor_ASC},
+						KeyColumnIDs:        []descpb.ColumnID{2},
+						KeySuffixColumnIDs:  []descpb.ColumnID{1, 3},
+						StoreColumnNames:    []string{"c"},
+						Version:             descpb.StrictIndexColumnIDGuaranteesVersion,
+					}}
+					tbl.NextIndexID = 3
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+		},
+	})
+}
+
+func TestCheckPrimaryIndexCoverage(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "primary index coverage",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					computeExpr := "col + 1"
+					for _, col := range []descpb.ColumnDescriptor{
+						{Name: "a", ID: 2, Type: types.Int},
+						{Name: "b", ID: 3, Type: types.Int, Nullable: true},
+						{Name: "c", ID: 4, Type: types.String},
+						{Name: "v", ID: 5, Type: types.Int, ComputeExpr: &computeExpr, Virtual: true, Nullable: true},
+					} {
+						tbl.Columns = append(tbl.Columns, col)
+						if !col.Virtual {
+							tbl.Families[0].ColumnNames = append(tbl.Families[0].ColumnNames, col.Name)
+							tbl.Families[0].ColumnIDs = append(tbl.Families[0].ColumnIDs, col.ID)
+						}
+					}
+					// A column being added is in a family, so it must be stored too.
+					tbl.Mutations = []descpb.DescriptorMutation{{
+						Descriptor_: &descpb.DescriptorMutation_Column{Column: &descpb.ColumnDescriptor{
+							Name: "m", ID: 6, Type: types.Int, Nullable: true,
+						}},
+						State:      descpb.DescriptorMutation_DELETE_AND_WRITE_ONLY,
+						Direction:  descpb.DescriptorMutation_ADD,
+						MutationID: 1,
+					}}
+					tbl.NextMutationID = 2
+					tbl.Families[0].ColumnNames = append(tbl.Families[0].ColumnNames, "m")
+					tbl.Families[0].ColumnIDs = append(tbl.Families[0].ColumnIDs, 6)
+					tbl.NextColumnID = 7
+					tbl.PrimaryIndex.StoreColumnNames = []string{"a"}
+					tbl.PrimaryIndex.StoreColumnIDs = []descpb.ColumnID{2}
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`incomplete primary index: relation "t" (51): primary index "t_pkey" doesn't store columns "b" (3), "c" (4), "m" (6)`,
+			},
+		},
+	})
+}
+
+func TestCheckKeyNullability(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "key nullability",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Columns[0].Nullable = true
+					// Unique secondary indexes may have nullable key columns, as long as
+					// the primary key columns are their key suffix.
+					tbl.Columns = append(tbl.Columns, descpb.ColumnDescriptor{
+						Name: "b", ID: 2, Type: types.Int, Nullable: true,
+					})
+					tbl.NextColumnID = 3
+					tbl.Families[0].ColumnNames = []string{"col", "b"}
+					tbl.Families[0].ColumnIDs = []descpb.ColumnID{1, 2}
+					tbl.PrimaryIndex.StoreColumnNames = []string{"b"}
+					tbl.PrimaryIndex.StoreColumnIDs = []descpb.ColumnID{2}
+					tbl.Indexes = []descpb.IndexDescriptor{{
+						Name:                "idx",
+						ID:                  2,
+						Unique:              true,
+						KeyColumnNames:      []string{"b"},
+						KeyColumnDirections: []descpb.IndexDescriptor_Direction{descpb.IndexDescriptor_ASC},
+						KeyColumnIDs:        []descpb.ColumnID{2},
+						KeySuffixColumnIDs:  []descpb.ColumnID{1},
+						Version:             descpb.StrictIndexColumnIDGuaranteesVersion,
+					}, {
+						Name:                "idx2",
+						ID:                  3,
+						Unique:              true,
+						KeyColumnNames:      []string{"b"},
+						KeyColumnDirections: []descpb.IndexDescriptor_Direction{descpb.IndexDescriptor_ASC},
+						KeyColumnIDs:        []descpb.ColumnID{2},
+						Version:             descpb.StrictIndexColumnIDGuaranteesVersion,
+					}}
+					tbl.NextIndexID = 4
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`nullable key column: relation "t" (51): primary index "t_pkey" key column "col" (1) is nullable`,
+				`nullable key column: relation "t" (51): unique index "idx2" key column "b" (2) is nullable, but the index lacks key suffix column "col" (1)`,
+			},
+		},
+	})
+}
+
+func TestCheckHiddenColumns(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "hidden columns",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					defaultExpr := "unique_rowid()"
+					tbl.Columns[0].Name = "rowid"
+					tbl.Columns[0].DefaultExpr = &defaultExpr
+					tbl.Families[0].ColumnNames = []string{"rowid"}
+					tbl.PrimaryIndex.KeyColumnNames = []string{"rowid"}
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`hidden column: relation "t" (51): column "rowid" (1) looks like the implicit row ID column of primary index "t_pkey" but isn't hidden`,
+			},
+		},
+	})
+}
+
+func TestCheckColumnFamilies(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "family default columns",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Columns = append(tbl.Columns, descpb.ColumnDescriptor{
+						Name: "b", ID: 2, Type: types.Int, Nullable: true,
+					})
+					tbl.NextColumnID = 3
+					tbl.Families = []descpb.ColumnFamilyDescriptor{
+						{ID: 0, Name: "primary", ColumnNames: []string{"b"}, ColumnIDs: []descpb.ColumnID{2}, DefaultColumnID: 3},
+						{ID: 1, Name: "f", ColumnNames: []string{"col"}, ColumnIDs: []descpb.ColumnID{1}, DefaultColumnID: 1},
+					}
+					tbl.NextFamilyID = 2
+					tbl.PrimaryIndex.StoreColumnNames = []string{"b"}
+					tbl.PrimaryIndex.StoreColumnIDs = []descpb.ColumnID{2}
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`column family: relation "t" (51): family "primary" (0) has default column ID 3 which is not in the family`,
+				`column family: relation "t" (51): family "f" (1) has primary key column "col" (1) as its default column`,
+			},
+		},
+		{
+			// Validation stops at the first of these.
+			name: "broken families",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					for _, col := range []descpb.ColumnDescriptor{
+						{Name: "b", ID: 2, Type: types.Int, Nullable: true},
+						{Name: "c", ID: 3, Type: types.Int, Nullable: true},
+						{Name: "d", ID: 4, Type: types.Int, Nullable: true},
+					} {
+						tbl.Columns = append(tbl.Columns, col)
+						tbl.PrimaryIndex.StoreColumnNames = append(tbl.PrimaryIndex.StoreColumnNames, col.Name)
+						tbl.PrimaryIndex.StoreColumnIDs = append(tbl.PrimaryIndex.StoreColumnIDs, col.ID)
+					}
+					tbl.NextColumnID = 5
+					tbl.Families = []descpb.ColumnFamilyDescriptor{
+						{ID: 0, Name: "f", ColumnNames: []string{"col", "x"}, ColumnIDs: []descpb.ColumnID{1, 5}},
+						{ID: 1, Name: "g", ColumnNames: []string{"b"}, ColumnIDs: []descpb.ColumnID{2, 3}},
+						{ID: 2, Name: "h", ColumnNames: []string{"z"}, ColumnIDs: []descpb.ColumnID{3}, DefaultColumnID: 2},
+					}
+					tbl.NextFamilyID = 3
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`column family: relation "t" (51): family "f" (0) references missing column ID 5`,
+				`column family: relation "t" (51): family "g" (1) has 2 column IDs but 1 column names`,
+				`column family: relation "t" (51): family "h" (2) column ID 3 has name "z", expected "c"`,
+				`column family: relation "t" (51): family "h" (2) contains column "c" (3) which is also in family "g" (1)`,
+				`column family: relation "t" (51): family "h" (2) has default column ID 2 which is not in the family`,
+				`column family: relation "t" (51): column "d" (4) is not in any family`,
+			},
+		},
+		{
+			name: "virtual columns",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					computeExpr := "col + 1"
+					for _, col := range []descpb.ColumnDescriptor{
+						{Name: "v", ID: 2, Type: types.Int, ComputeExpr: &computeExpr, Virtual: true, Nullable: true},
+						{Name: "w", ID: 3, Type: types.Int, ComputeExpr: &computeExpr, Virtual: true, Nullable: true},
+					} {
+						tbl.Columns = append(tbl.Columns, col)
+						tbl.Families[0].ColumnNames = append(tbl.Families[0].ColumnNames, col.Name)
+						tbl.Families[0].ColumnIDs = append(tbl.Families[0].ColumnIDs, col.ID)
+					}
+					tbl.NextColumnID = 4
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`column family: relation "t" (51): family "f" (0) contains virtual column "v" (2)`,
+				`column family: relation "t" (51): family "f" (0) contains virtual column "w" (3)`,
+			},
+		},
+	})
+}
+
+func TestCheckPartitioning(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "partitioning",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.PrimaryIndex.Partitioning = descpb.PartitioningDescriptor{
+						NumColumns: 1,
+						List: []descpb.PartitioningDescriptor_List{
+							{Name: "p", Subpartitioning: descpb.PartitioningDescriptor{
+								NumColumns: 1,
+								Range:      []descpb.PartitioningDescriptor_Range{{Name: "q"}},
+							}},
+							{Name: "p"},
+						},
+					}
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`partitioning: relation "t" (51): partitioning of partition "p" of index "t_pkey" needs 2 key columns, but the index has 1`,
+				`partitioning: relation "t" (51): index "t_pkey" has more than one partition named "p"`,
+			},
+		},
+	})
+}
+
+func TestCheckColumnTypes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "column types",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					for _, col := range []descpb.ColumnDescriptor{
+						{Name: "a", ID: 2},
+						{Name: "b", ID: 3, Type: &types.T{InternalType: types.InternalType{Family: 99}}},
+						{Name: "c", ID: 4, Type: &types.T{InternalType: types.InternalType{
+							Family: types.UuidFamily, Oid: 9999,
+						}}},
+					} {
+						col.Nullable = true
+						tbl.Columns = append(tbl.Columns, col)
+						tbl.Families[0].ColumnNames = append(tbl.Families[0].ColumnNames, col.Name)
+						tbl.Families[0].ColumnIDs = append(tbl.Families[0].ColumnIDs, col.ID)
+						tbl.PrimaryIndex.StoreColumnNames = append(tbl.PrimaryIndex.StoreColumnNames, col.Name)
+						tbl.PrimaryIndex.StoreColumnIDs = append(tbl.PrimaryIndex.StoreColumnIDs, col.ID)
+					}
+					tbl.NextColumnID = 5
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`column type: relation "t" (51): column "a" (2) has an invalid type: no type`,
+				`column type: relation "t" (51): column "b" (3) has an invalid type: unknown family 99 (OID 0)`,
+				`column type: relation "t" (51): column "c" (4) has an invalid type: unknown OID 9999 of family UuidFamily`,
+			},
+		},
+	})
+}
+
+func TestCheckSequenceOptions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "sequence options",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "s"
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("s")
+					tbl.SequenceOpts = &descpb.TableDescriptor_SequenceOpts{
+						Increment: 0, MinValue: 100, MaxValue: 1, Start: 1, CacheSize: -1,
+					}
+				}))},
+				dbRow(t),
+				{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "u"
+					tbl.ID = 53
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("u")
+					tbl.SequenceOpts = &descpb.TableDescriptor_SequenceOpts{
+						Increment: -1, MinValue: 1, MaxValue: 100, Start: 200,
+					}
+				}))},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("s", 51), dbNamespaceRow, tableNamespaceRow("u", 53),
+			},
+			expected: []string{
+				`sequence options: relation "s" (51): has an increment of zero`,
+				`sequence options: relation "s" (51): has a minimum value 100 greater than its maximum value 1`,
+				`sequence options: relation "s" (51): has a negative cache size -1`,
+				`sequence options: relation "u" (53): has a start value 200 outside of its range [1, 100]`,
+			},
+		},
+	})
+}
+
+func TestCheckIDCounters(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "ID counters",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.NextColumnID = 1
+					tbl.NextFamilyID = 0
+					tbl.NextIndexID = 1
+					tbl.Mutations = []descpb.DescriptorMutation{{
+						Descriptor_: &descpb.DescriptorMutation_Index{Index: &descpb.IndexDescriptor{
+							Name:                "idx",
+							ID:                  2,
+							KeyColumnNames:      []string{"col"},
+							KeyColumnDirections: []descpb.IndexDescriptor_Direction{descpb.IndexDescriptor_ASC},
+							KeyColumnIDs:        []descpb.ColumnID{1},
+							Version:             descpb.StrictIndexColumnIDGuaranteesVersion,
+						}},
+						State:      descpb.DescriptorMutation_DELETE_ONLY,
+						Direction:  descpb.DescriptorMutation_ADD,
+						MutationID: 1,
+					}}
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`stale ID counter: relation "t" (51): NextColumnID 1 is not greater than ID 1 of column "col"`,
+				`stale ID counter: relation "t" (51): NextFamilyID 0 is not greater than ID 0 of family "f"`,
+				`stale ID counter: relation "t" (51): NextIndexID 1 is not greater than ID 1 of index "t_pkey"`,
+				`stale ID counter: relation "t" (51): NextIndexID 1 is not greater than ID 2 of index "idx"`,
+				`stale ID counter: relation "t" (51): NextMutationID 1 is not greater than ID 1 of mutation in state DELETE_ONLY`,
+			},
+		},
+	})
+}
+
+func TestCheckMutations(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "mutations",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					b := descpb.ColumnDescriptor{Name: "b", ID: 2, Type: types.Int, Nullable: true}
+					tbl.Families[0].ColumnNames = append(tbl.Families[0].ColumnNames, b.Name)
+					tbl.Families[0].ColumnIDs = append(tbl.Families[0].ColumnIDs, b.ID)
+					tbl.NextColumnID = 3
+					tbl.Mutations = []descpb.DescriptorMutation{
+						{
+							Descriptor_: &descpb.DescriptorMutation_Column{Column: &b},
+							State:       descpb.DescriptorMutation_DELETE_ONLY,
+							Direction:   descpb.DescriptorMutation_ADD,
+							MutationID:  2,
+						},
+						{
+							Descriptor_: &descpb.DescriptorMutation_Column{Column: &tbl.Columns[0]},
+							State:       descpb.DescriptorMutation_DELETE_ONLY,
+							Direction:   descpb.DescriptorMutation_DROP,
+							MutationID:  1,
+						},
+						{
+							Descriptor_: &descpb.DescriptorMutation_Index{Index: &tbl.PrimaryIndex},
+							State:       descpb.DescriptorMutation_DELETE_ONLY,
+							Direction:   descpb.DescriptorMutation_ADD,
+							MutationID:  2,
+						},
+						{
+							Descriptor_: &descpb.DescriptorMutation_Column{Column: &b},
+							State:       descpb.DescriptorMutation_DELETE_ONLY,
+							Direction:   descpb.DescriptorMutation_DROP,
+							MutationID:  2,
+						},
+					}
+					tbl.NextMutationID = 3
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`mutation: relation "t" (51): mutation 1 has mutation ID 1, which is lower than mutation ID 2 of mutation 0`,
+				`mutation: relation "t" (51): mutation 1 drops column "col" (1), which is still public`,
+				`mutation: relation "t" (51): mutation 2 adds index "t_pkey" (1), which is already public`,
+				`mutation: relation "t" (51): mutation 3 drops column "b" (2), which mutation 0 also refers to`,
+			},
+		},
+	})
+}
+
+func TestCheckEnumMembers(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "enum members",
+			descTable: doctor.DescriptorTable{
+				dbRow(t),
+				{
+					ID: 54,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Type{
+						Type: &descpb.TypeDescriptor{
+							Name:           "typ",
+							ID:             54,
+							ParentID:       52,
+							ParentSchemaID: keys.PublicSchemaID,
+							Kind:           descpb.TypeDescriptor_ENUM,
+							EnumMembers: []descpb.TypeDescriptor_EnumMember{
+								{LogicalRepresentation: "a", PhysicalRepresentation: []byte{0x40}},
+								{LogicalRepresentation: "", PhysicalRepresentation: []byte{0x80}},
+								{LogicalRepresentation: "a", PhysicalRepresentation: []byte{0x90}},
+								{LogicalRepresentation: "b"},
+								{LogicalRepresentation: "c", PhysicalRepresentation: []byte{0x80}},
+								{LogicalRepresentation: "d", PhysicalRepresentation: []byte{0x70}},
+							},
+						},
+					}}),
+				},
+			},
+			namespaceTable: doctor.NamespaceTable{dbNamespaceRow, tableNamespaceRow("typ", 54)},
+			expected: []string{
+				`enum member: type "typ" (54): enum member 1 has an empty name`,
+				`enum member: type "typ" (54): enum member 2 ("a") has the same name as member 0`,
+				`enum member: type "typ" (54): enum member 3 ("b") has an empty physical representation`,
+				`enum member: type "typ" (54): enum member 4 ("c") has the same physical representation 80 as member 1 ("")`,
+				`enum member: type "typ" (54): enum member 5 ("d") has physical representation 70, which isn't greater than 80 of member 4 ("c")`,
+			},
+		},
+	})
+}
+
+func TestCheckFormatVersion(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "format versions",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.FormatVersion = descpb.BaseFormatVersion
+					tbl.PrimaryIndex.InterleavedBy = []descpb.ForeignKeyReference{{Table: 53, Index: 1}}
+				}))},
+				dbRow(t),
+				{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "u"
+					tbl.ID = 53
+					tbl.FormatVersion = descpb.FamilyFormatVersion
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("u")
+					tbl.PrimaryIndex.Interleave.Ancestors = []descpb.InterleaveDescriptor_Ancestor{
+						{TableID: 51, IndexID: 1, SharedPrefixLen: 1},
+					}
+				}))},
+				{ID: 54, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "v"
+					tbl.ID = 54
+					tbl.FormatVersion = 9
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("v")
+				}))},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("u", 53), tableNamespaceRow("v", 54),
+			},
+			expected: []string{
+				`format version: relation "t" (51): has format version 1, which predates column families, but has column families`,
+				`format version: relation "t" (51): has format version 1, which predates interleaved tables, but index "t_pkey" is interleaved`,
+				`format version: relation "u" (53): has format version 2, which predates interleaved tables, but index "u_pkey" is interleaved`,
+				`format version: relation "v" (54): has format version 9, but the latest format version supported is 3`,
+			},
+		},
+	})
+}
+
+func TestCheckIndexVersions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "index versions",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.PrimaryIndex.Version = 9
+					tbl.Indexes = []descpb.IndexDescriptor{{
+						Name:                "idx",
+						ID:                  2,
+						KeyColumnNames:      []string{"col"},
+						KeyColumnDirections: []descpb.IndexDescriptor_Direction{descpb.IndexDescriptor_ASC},
+						KeyColumnIDs:        []descpb.ColumnID{1},
+						Version:             descpb.LatestNonPrimaryIndexDescriptorVersion + 1,
+					}, {
+						Name:                "inv",
+						ID:                  3,
+						Type:                descpb.IndexDescriptor_INVERTED,
+						KeyColumnNames:      []string{"col"},
+						KeyColumnDirections: []descpb.IndexDescriptor_Direction{descpb.IndexDescriptor_ASC},
+						KeyColumnIDs:        []descpb.ColumnID{1},
+						Version:             descpb.SecondaryIndexFamilyFormatVersion,
+					}}
+					tbl.NextIndexID = 4
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`index version: relation "t" (51): primary index "t_pkey" has version 9, but the latest version supported for primary indexes is 4`,
+				`index version: relation "t" (51): secondary index "idx" has version 4, but the latest version supported for secondary indexes is 3`,
+				`obsolete index version: relation "t" (51): secondary index "inv" has obsolete version 1, which can't be upgraded to 3 without rebuilding it`,
+			},
+		},
+	})
+}
+
+func TestCheckNamespaceEntries(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "schema namespace entries",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51),
+				dbNamespaceRow,
+				{NameInfo: descpb.NameInfo{ParentID: 52, Name: "public"}, ID: keys.PublicSchemaID},
+				{NameInfo: descpb.NameInfo{ParentID: 52, Name: "pg_temp_1_1"}, ID: 60},
+				tableNamespaceRow("public", keys.PublicSchemaID),
+				{NameInfo: descpb.NameInfo{ParentID: 61, Name: "public"}, ID: keys.PublicSchemaID},
+				{NameInfo: descpb.NameInfo{ParentID: 51, Name: "pg_temp_2_2"}, ID: 62},
+				{NameInfo: descpb.NameInfo{ParentID: 52, Name: "pg_temp_3_3"}, ID: 51},
+			},
+			expected: []string{
+				`duplicate namespace entry: relation "t" (51): referenced by 2 namespace entries: (52, 29, t), (52, 0, pg_temp_3_3)`,
+				`invalid namespace entry: namespace entry "public" (29): refers to the public schema, whose entries must be named "public" and have a database as their parent`,
+				`invalid namespace entry: namespace entry "public" (29): public schema belongs to missing database 61`,
+				`invalid namespace entry: namespace entry "pg_temp_2_2" (62): temporary schema belongs to relation "t" (51), which is not a database`,
+				`invalid namespace entry: namespace entry "pg_temp_3_3" (51): temporary schema has the ID of relation "t" (51)`,
+			},
+		},
+	})
+}
+
+func TestCheckDrainingNames(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "draining names",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.DrainingNames = []descpb.NameInfo{
+						{ParentID: 52, ParentSchemaID: keys.PublicSchemaID, Name: "old"},
+					}
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("old", 51),
+			},
+			jobsTable: doctor.JobsTable{},
+			expected: []string{
+				`stale draining name: relation "t" (51): draining name (52, 29, old) still has its namespace entry, but no unfinished schema change job drains it`,
+			},
+		},
+	})
+}
+
+func TestCheckReservedID(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	systemDBRow := doctor.DescriptorTableRow{
+		ID: keys.SystemDatabaseID,
+		DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+			Database: &descpb.DatabaseDescriptor{Name: "system", ID: keys.SystemDatabaseID},
+		}}),
+	}
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "reserved IDs",
+			descTable: doctor.DescriptorTable{
+				{ID: 40, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "u"
+					tbl.ID = 40
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("u")
+				}))},
+				dbRow(t),
+				{ID: 60, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "v"
+					tbl.ID = 60
+					tbl.ParentID = keys.SystemDatabaseID
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("v")
+				}))},
+				systemDBRow,
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("u", 40),
+				dbNamespaceRow,
+				{NameInfo: descpb.NameInfo{ParentID: keys.SystemDatabaseID, ParentSchemaID: keys.PublicSchemaID, Name: "v"}, ID: 60},
+				{NameInfo: descpb.NameInfo{Name: "system"}, ID: keys.SystemDatabaseID},
+			},
+			expected: []string{
+				`reserved ID: relation "u" (40): user descriptor ID 40 is reserved for system descriptors, user IDs start at 50`,
+				`reserved ID: relation "v" (60): system descriptor ID 60 is outside of the reserved IDs, which are below 50`,
+			},
+		},
+		{
+			// Without the system database, the descriptors aren't a whole cluster.
+			name: "reserved IDs without system database",
+			descTable: doctor.DescriptorTable{
+				{ID: 40, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "u"
+					tbl.ID = 40
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("u")
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("u", 40), dbNamespaceRow},
+		},
+	})
+}
+
+func TestCheckVersion(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "versions",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.ModificationTime = hlc.Timestamp{WallTime: 1e9}
+				}))},
+				{ID: 52, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+					Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52, Version: 1},
+				}})},
+				{
+					ID: 53,
+					DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+						tbl.Name = "u"
+						tbl.ID = 53
+						tbl.Version = 2
+						tbl.ModificationTime = hlc.Timestamp{WallTime: 3e9}
+					})),
+					ModTime: hlc.Timestamp{WallTime: 4e9},
+				},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("u", 53),
+			},
+			opts: []doctor.ExamineOption{doctor.WithVersionCheck(hlc.Timestamp{WallTime: 2e9})},
+			expected: []string{
+				`version: relation "t" (51): has version 0`,
+				`version: database "db" (52): version 1 has no modification time`,
+				`version: relation "u" (53): version 2 has modification time 3.000000000,0, which is after now (2.000000000,0)`,
+			},
+		},
+	})
+}
+
+func TestCheckPrivileges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "privileges",
+			descTable: doctor.DescriptorTable{
+				{
+					ID: 51,
+					// Marshal the descriptor as is, toBytes would fix its privileges.
+					DescBytes: func() []byte {
+						desc := modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+							tbl.Privileges = &descpb.PrivilegeDescriptor{
+								Users: []descpb.UserPrivileges{
+									{
+										UserProto:  security.RootUserName().EncodeProto(),
+										Privileges: privilege.SELECT.Mask(),
+									},
+									{
+										UserProto:  security.MakeSQLUsernameFromPreNormalizedString("alice").EncodeProto(),
+										Privileges: privilege.SELECT.Mask() | privilege.CONNECT.Mask() | 1<<20,
+									},
+									{
+										UserProto: security.MakeSQLUsernameFromPreNormalizedString("bob").EncodeProto(),
+									},
+								},
+								Version: descpb.Version21_2,
+							}
+						})
+						res, err := protoutil.Marshal(desc)
+						require.NoError(t, err)
+						return res
+					}(),
+				},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`privilege: relation "t" (51): has no owner`,
+				`privilege: relation "t" (51): superuser admin has no privileges`,
+				`privilege: relation "t" (51): superuser root has privileges SELECT, expected exactly ALL`,
+				`privilege: relation "t" (51): user alice has privileges CONNECT, unknown bits 0x100000, which are invalid for a table`,
+				`privilege: relation "t" (51): user bob has no privileges`,
+			},
+		},
+	})
+}
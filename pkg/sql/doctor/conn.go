@@ -0,0 +1,293 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"context"
+	gosql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+
+	apd "github.com/cockroachdb/apd/v2"
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/pgwire/pgcode"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+	"github.com/lib/pq"
+)
+
+// Queryer runs the queries through which the system tables are read. It lets
+// clients which don't go through database/sql, such as the CLI's, read the
+// system tables over their own connection.
+type Queryer interface {
+	// Query runs query and returns its rows.
+	Query(ctx context.Context, query string) (Rows, error)
+}
+
+// Rows are the rows returned by a Queryer.
+type Rows interface {
+	// Next fills values with the next row, as returned by the driver, or
+	// returns io.EOF once all the rows were read.
+	Next(values []driver.Value) error
+	Close() error
+}
+
+// ExamineFromConn reads the descriptor, namespace and jobs tables through db
+// and runs Examine over them. See FromQueryer for how the tables are read.
+func ExamineFromConn(
+	ctx context.Context, db *gosql.DB, verbose bool, w io.Writer, opts ...ExamineOption,
+) (ok bool, err error) {
+	return ExamineFromQueryer(ctx, dbQueryer{db: db}, verbose, w, opts...)
+}
+
+// ExamineFromQueryer reads the descriptor, namespace and jobs tables through q
+// and runs Examine over them. See FromQueryer for how the tables are read.
+func ExamineFromQueryer(
+	ctx context.Context, q Queryer, verbose bool, w io.Writer, opts ...ExamineOption,
+) (ok bool, err error) {
+	descTable, namespaceTable, jobsTable, err := FromQueryer(ctx, q, w)
+	if err != nil {
+		return false, err
+	}
+	return Examine(ctx, descTable, namespaceTable, jobsTable, verbose, w, opts...)
+}
+
+// FromQueryer reads the descriptor, namespace and jobs tables through q.
+// Descriptor table rows with a NULL descriptor are left out, with a note
+// written to w: the namespace entries pointing to them are then reported as
+// referring to missing descriptors. Namespace entries with a NULL ID get an
+// invalid ID. Clusters from before 20.2 have no MVCC timestamps for their
+// descriptors, which are then taken to be the current time, and clusters from
+// before 20.1 have no parent schema IDs in their namespace table, which are
+// then those of the public schema for tables and none for databases.
+func FromQueryer(
+	ctx context.Context, q Queryer, w io.Writer,
+) (DescriptorTable, NamespaceTable, JobsTable, error) {
+	descTable, err := queryDescriptorTable(ctx, q, w)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	namespaceTable, err := queryNamespaceTable(ctx, q)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	jobsTable, err := queryJobsTable(ctx, q)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return descTable, namespaceTable, jobsTable, nil
+}
+
+// queryWithFallback runs stmt through q, or fallbackStmt instead if stmt
+// refers to a column which doesn't exist in this version.
+func queryWithFallback(ctx context.Context, q Queryer, stmt, fallbackStmt string) (Rows, error) {
+	rows, err := q.Query(ctx, stmt)
+	if pqErr := (*pq.Error)(nil); errors.As(err, &pqErr) &&
+		pgcode.MakeCode(string(pqErr.Code)) == pgcode.UndefinedColumn {
+		return q.Query(ctx, fallbackStmt)
+	}
+	return rows, err
+}
+
+// forEachRow calls fn with the values of each of the rows, which have n
+// columns, and closes them.
+func forEachRow(rows Rows, n int, fn func(vals []driver.Value) error) (retErr error) {
+	defer func() { retErr = errors.CombineErrors(retErr, rows.Close()) }()
+	vals := make([]driver.Value, n)
+	for {
+		if err := rows.Next(vals); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(vals); err != nil {
+			return err
+		}
+	}
+}
+
+// queryDescriptorTable reads the descriptor table through q.
+func queryDescriptorTable(ctx context.Context, q Queryer, w io.Writer) (DescriptorTable, error) {
+	rows, err := queryWithFallback(ctx, q, `
+SELECT id, descriptor, crdb_internal_mvcc_timestamp
+FROM system.descriptor ORDER BY id`, `
+SELECT id, descriptor, NULL
+FROM system.descriptor ORDER BY id`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query descriptor table")
+	}
+	descTable := make(DescriptorTable, 0)
+	err = forEachRow(rows, 3, func(vals []driver.Value) error {
+		var row DescriptorTableRow
+		var ok bool
+		if row.ID, ok = vals[0].(int64); !ok {
+			return errors.Errorf("unexpected descriptor id: %T of %v", vals[0], vals[0])
+		}
+		if vals[1] == nil {
+			fmt.Fprintf(w, "Skipping descriptor %d, which is NULL.\n", row.ID)
+			return nil
+		}
+		if row.DescBytes, ok = vals[1].([]byte); !ok {
+			return errors.Errorf("unexpected descriptor %d: %T of %v", row.ID, vals[1], vals[1])
+		}
+		if vals[2] == nil {
+			row.ModTime = hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
+		} else {
+			modTime, ok := vals[2].([]byte)
+			if !ok {
+				return errors.Errorf("unexpected timestamp of descriptor %d: %T of %v",
+					row.ID, vals[2], vals[2])
+			}
+			decimal, _, err := apd.NewFromString(string(modTime))
+			if err != nil {
+				return errors.Wrapf(err, "failed to parse timestamp of descriptor %d", row.ID)
+			}
+			if row.ModTime, err = tree.DecimalToHLC(decimal); err != nil {
+				return errors.Wrapf(err, "failed to parse timestamp of descriptor %d", row.ID)
+			}
+		}
+		descTable = append(descTable, row)
+		return nil
+	})
+	return descTable, errors.Wrap(err, "failed to read descriptor table")
+}
+
+// queryNamespaceTable reads the namespace table through q.
+func queryNamespaceTable(ctx context.Context, q Queryer) (NamespaceTable, error) {
+	rows, err := queryWithFallback(ctx, q,
+		`SELECT "parentID", "parentSchemaID", name, id FROM system.namespace`, `
+SELECT "parentID", CASE WHEN "parentID" = 0 THEN 0 ELSE 29 END, name, id
+FROM system.namespace`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query namespace table")
+	}
+	namespaceTable := make(NamespaceTable, 0)
+	err = forEachRow(rows, 4, func(vals []driver.Value) error {
+		parentID, ok := vals[0].(int64)
+		if !ok {
+			return errors.Errorf("unexpected parent id: %T of %v", vals[0], vals[0])
+		}
+		parentSchemaID, ok := vals[1].(int64)
+		if !ok {
+			return errors.Errorf("unexpected parent schema id: %T of %v", vals[1], vals[1])
+		}
+		name, ok := vals[2].(string)
+		if !ok {
+			return errors.Errorf("unexpected name: %T of %v", vals[2], vals[2])
+		}
+		row := NamespaceTableRow{
+			NameInfo: descpb.NameInfo{
+				ParentID:       descpb.ID(parentID),
+				ParentSchemaID: descpb.ID(parentSchemaID),
+				Name:           name,
+			},
+			ID: int64(descpb.InvalidID),
+		}
+		if vals[3] != nil {
+			if row.ID, ok = vals[3].(int64); !ok {
+				return errors.Errorf("unexpected id of %q: %T of %v", name, vals[3], vals[3])
+			}
+		}
+		namespaceTable = append(namespaceTable, row)
+		return nil
+	})
+	return namespaceTable, errors.Wrap(err, "failed to read namespace table")
+}
+
+// queryJobsTable reads the jobs table through q.
+func queryJobsTable(ctx context.Context, q Queryer) (JobsTable, error) {
+	rows, err := q.Query(ctx, `SELECT id, status, payload, progress FROM system.jobs`)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query jobs table")
+	}
+	jobsTable := make(JobsTable, 0)
+	err = forEachRow(rows, 4, func(vals []driver.Value) error {
+		id, ok := vals[0].(int64)
+		if !ok {
+			return errors.Errorf("unexpected job id: %T of %v", vals[0], vals[0])
+		}
+		status, ok := vals[1].(string)
+		if !ok {
+			return errors.Errorf("unexpected status of job %d: %T of %v", id, vals[1], vals[1])
+		}
+		payload, ok := vals[2].([]byte)
+		if !ok {
+			return errors.Errorf("unexpected payload of job %d: %T of %v", id, vals[2], vals[2])
+		}
+		md := jobs.JobMetadata{ID: jobspb.JobID(id), Status: jobs.Status(status)}
+		md.Payload = &jobspb.Payload{}
+		if err := protoutil.Unmarshal(payload, md.Payload); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal payload of job %d", id)
+		}
+		// Progress is nullable, unlike the payload.
+		progress, _ := vals[3].([]byte)
+		md.Progress = &jobspb.Progress{}
+		if err := protoutil.Unmarshal(progress, md.Progress); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal progress of job %d", id)
+		}
+		jobsTable = append(jobsTable, md)
+		return nil
+	})
+	return jobsTable, errors.Wrap(err, "failed to read jobs table")
+}
+
+// dbQueryer is a Queryer reading through a *gosql.DB.
+type dbQueryer struct {
+	db *gosql.DB
+}
+
+// Query implements Queryer.
+func (q dbQueryer) Query(ctx context.Context, query string) (Rows, error) {
+	rows, err := q.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return dbRows{rows: rows}, nil
+}
+
+// dbRows are the Rows of a dbQueryer.
+type dbRows struct {
+	rows *gosql.Rows
+}
+
+// Next implements Rows. Scanning into an interface{} keeps the value returned
+// by the driver, copying []byte values.
+func (r dbRows) Next(values []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	vals := make([]interface{}, len(values))
+	dest := make([]interface{}, len(values))
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	for i, v := range vals {
+		values[i] = v
+	}
+	return nil
+}
+
+// Close implements Rows.
+func (r dbRows) Close() error {
+	return r.rows.Close()
+}
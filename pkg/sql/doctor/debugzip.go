@@ -0,0 +1,154 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/cockroach/pkg/util/timeutil"
+	"github.com/cockroachdb/errors"
+)
+
+// maxRowSize is the maximum size of a row of a debug zip table.
+const maxRowSize = 50 << 20 // 50 MiB
+
+// ReadDescriptorTable reads the descriptor table from the contents of the
+// system.descriptor.txt file of a debug zip: a header line followed by
+// tab-separated fields, with the descriptor ID first and the hex-encoded
+// descriptor last. The rows don't carry their MVCC timestamp, the current time
+// is used instead. The descriptors are checked to unmarshal as they're read,
+// so that a corrupt row is reported along with its line.
+//
+// The whole table is read into memory rather than examined as it's read:
+// upgrading and validating a descriptor requires the descriptors it
+// references, and as references to parent databases and schemas have no
+// back-references, no descriptor can be known to be no longer needed before
+// the last row is read.
+func ReadDescriptorTable(r io.Reader) (DescriptorTable, error) {
+	descTable := make(DescriptorTable, 0)
+	ts := hlc.Timestamp{WallTime: timeutil.Now().UnixNano()}
+	err := readRows(r, func(fields []string) error {
+		id, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse descriptor id %s", fields[0])
+		}
+		descBytes, err := hex.DecodeString(fields[len(fields)-1])
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode hex descriptor %d", id)
+		}
+		var d descpb.Descriptor
+		if err := protoutil.Unmarshal(descBytes, &d); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal descriptor %d", id)
+		}
+		descTable = append(descTable, DescriptorTableRow{ID: id, DescBytes: descBytes, ModTime: ts})
+		return nil
+	})
+	return descTable, err
+}
+
+// ReadNamespaceTable reads the namespace table from the contents of the
+// system.namespace.txt file of a debug zip: a header line followed by the
+// tab-separated parent ID, parent schema ID, name and ID of each entry. A NULL
+// ID is read as an invalid ID.
+func ReadNamespaceTable(r io.Reader) (NamespaceTable, error) {
+	namespaceTable := make(NamespaceTable, 0)
+	err := readRows(r, func(fields []string) error {
+		if len(fields) < 4 {
+			return errors.Newf("expected 4 fields in namespace row, found %d", len(fields))
+		}
+		parentID, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse parent id %s", fields[0])
+		}
+		parentSchemaID, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse parent schema id %s", fields[1])
+		}
+		id := int64(descpb.InvalidID)
+		if fields[3] != "NULL" {
+			if id, err = strconv.ParseInt(fields[3], 10, 64); err != nil {
+				return errors.Wrapf(err, "failed to parse id %s", fields[3])
+			}
+		}
+		namespaceTable = append(namespaceTable, NamespaceTableRow{
+			NameInfo: descpb.NameInfo{
+				ParentID:       descpb.ID(parentID),
+				ParentSchemaID: descpb.ID(parentSchemaID),
+				Name:           fields[2],
+			},
+			ID: id,
+		})
+		return nil
+	})
+	return namespaceTable, err
+}
+
+// ReadJobsTable reads the jobs table from the contents of the system.jobs.txt
+// file of a debug zip: a header line followed by tab-separated fields, with
+// the job ID and status first and the hex-encoded payload and progress last.
+func ReadJobsTable(r io.Reader) (JobsTable, error) {
+	jobsTable := make(JobsTable, 0)
+	err := readRows(r, func(fields []string) error {
+		if len(fields) < 4 {
+			return errors.Newf("expected at least 4 fields in jobs row, found %d", len(fields))
+		}
+		id, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse job id %s", fields[0])
+		}
+		md := jobs.JobMetadata{ID: jobspb.JobID(id), Status: jobs.Status(fields[1])}
+		last := len(fields) - 1
+		payloadBytes, err := hex.DecodeString(fields[last-1])
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode hex payload of job %d", id)
+		}
+		md.Payload = &jobspb.Payload{}
+		if err := protoutil.Unmarshal(payloadBytes, md.Payload); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal payload of job %d", id)
+		}
+		progressBytes, err := hex.DecodeString(fields[last])
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode hex progress of job %d", id)
+		}
+		md.Progress = &jobspb.Progress{}
+		if err := protoutil.Unmarshal(progressBytes, md.Progress); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal progress of job %d", id)
+		}
+		jobsTable = append(jobsTable, md)
+		return nil
+	})
+	return jobsTable, err
+}
+
+// readRows calls fn with the tab-separated fields of each row read from r,
+// skipping the header line and empty lines.
+func readRows(r io.Reader, fn func(fields []string) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), maxRowSize)
+	for line := 1; sc.Scan(); line++ {
+		if line == 1 || sc.Text() == "" {
+			continue
+		}
+		if err := fn(strings.Split(sc.Text(), "\t")); err != nil {
+			return errors.Wrapf(err, "line %d", line)
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/jobs"
+	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadDebugZipTables(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+		{
+			ID: 52,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+				Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+			}}),
+		},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+		{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "with space"}, ID: 54},
+		{NameInfo: descpb.NameInfo{Name: "null"}, ID: int64(descpb.InvalidID)},
+	}
+	payload := &jobspb.Payload{
+		Description:   "schema change",
+		DescriptorIDs: []descpb.ID{51},
+		Details:       jobspb.WrapPayloadDetails(jobspb.SchemaChangeDetails{}),
+	}
+	progress := &jobspb.Progress{
+		Details: jobspb.WrapProgressDetails(jobspb.SchemaChangeProgress{}),
+	}
+
+	// Write the tables in the format of a debug zip.
+	var descRows, nsRows, jobsRows strings.Builder
+	descRows.WriteString("id\tdescriptor\thex_descriptor\n")
+	for _, row := range descTable {
+		fmt.Fprintf(&descRows, "%d\t%q\t%x\n", row.ID, row.DescBytes, row.DescBytes)
+	}
+	nsRows.WriteString("parentID\tparentSchemaID\tname\tid\n")
+	for _, row := range namespaceTable {
+		id := fmt.Sprint(row.ID)
+		if row.ID == int64(descpb.InvalidID) {
+			id = "NULL"
+		}
+		fmt.Fprintf(&nsRows, "%d\t%d\t%s\t%s\n", row.ParentID, row.ParentSchemaID, row.Name, id)
+	}
+	payloadBytes, err := protoutil.Marshal(payload)
+	require.NoError(t, err)
+	progressBytes, err := protoutil.Marshal(progress)
+	require.NoError(t, err)
+	jobsRows.WriteString("id\tstatus\tcreated\tpayload\tprogress\n")
+	fmt.Fprintf(&jobsRows, "123\trunning\t2021-01-01 00:00:00\t%x\t%x\n", payloadBytes, progressBytes)
+
+	actualDescTable, err := doctor.ReadDescriptorTable(strings.NewReader(descRows.String()))
+	require.NoError(t, err)
+	require.Len(t, actualDescTable, len(descTable))
+	for i, row := range actualDescTable {
+		require.Equal(t, descTable[i].ID, row.ID)
+		require.Equal(t, descTable[i].DescBytes, row.DescBytes)
+	}
+	actualNamespaceTable, err := doctor.ReadNamespaceTable(strings.NewReader(nsRows.String()))
+	require.NoError(t, err)
+	require.Equal(t, namespaceTable, actualNamespaceTable)
+	actualJobsTable, err := doctor.ReadJobsTable(strings.NewReader(jobsRows.String()))
+	require.NoError(t, err)
+	require.Len(t, actualJobsTable, 1)
+	require.Equal(t, jobspb.JobID(123), actualJobsTable[0].ID)
+	require.Equal(t, jobs.StatusRunning, actualJobsTable[0].Status)
+	require.Equal(t, payload, actualJobsTable[0].Payload)
+	require.Equal(t, progress, actualJobsTable[0].Progress)
+
+	_, err = doctor.ReadDescriptorTable(strings.NewReader("id\tdescriptor\thex_descriptor\n51\t\tnothex\n"))
+	require.EqualError(t, err, "line 2: failed to decode hex descriptor 51: encoding/hex: invalid byte: U+006E 'n'")
+}
@@ -17,7 +17,9 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"runtime"
 	"strings"
+	"sync/atomic"
 
 	"github.com/cockroachdb/cockroach/pkg/jobs"
 	"github.com/cockroachdb/cockroach/pkg/jobs/jobspb"
@@ -27,6 +29,8 @@ import (
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkeys"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkv"
 	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/cockroach/pkg/util/ctxgroup"
 	"github.com/cockroachdb/cockroach/pkg/util/hlc"
 	"github.com/cockroachdb/cockroach/pkg/util/log"
 	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
@@ -67,7 +71,7 @@ func (jt JobsTable) GetJobMetadata(jobID jobspb.JobID) (*jobs.JobMetadata, error
 }
 
 func newDescGetter(
-	ctx context.Context, stdout io.Writer, descRows []DescriptorTableRow, nsRows []NamespaceTableRow,
+	ctx context.Context, e *examination, descRows []DescriptorTableRow, nsRows []NamespaceTableRow,
 ) (catalog.MapDescGetter, error) {
 	ddg := catalog.MapDescGetter{
 		Descriptors: make(map[descpb.ID]catalog.Descriptor, len(descRows)),
@@ -93,7 +97,9 @@ func newDescGetter(
 		b := catalogkv.NewBuilderWithMVCCTimestamp(&d, r.ModTime)
 		if b != nil {
 			if err := b.RunPostDeserializationChanges(ctx, ddg); err != nil {
-				descReport(stdout, ddg.Descriptors[descpb.ID(r.ID)], "failed to upgrade descriptor: %v", err)
+				if desc := ddg.Descriptors[descpb.ID(r.ID)]; e.inScope(descpb.ID(r.ID), desc) {
+					e.descReport(desc, UpgradeFailure, "failed to upgrade descriptor: %v", err)
+				}
 			} else {
 				ddg.Descriptors[descpb.ID(r.ID)] = b.BuildImmutable()
 			}
@@ -105,7 +111,8 @@ func newDescGetter(
 	return ddg, nil
 }
 
-// Examine runs a suite of consistency checks over system tables.
+// Examine runs a suite of consistency checks over system tables. The options
+// only apply to the examination of the descriptor table.
 func Examine(
 	ctx context.Context,
 	descTable DescriptorTable,
@@ -113,8 +120,9 @@ func Examine(
 	jobsTable JobsTable,
 	verbose bool,
 	stdout io.Writer,
+	opts ...ExamineOption,
 ) (ok bool, err error) {
-	descOk, err := ExamineDescriptors(ctx, descTable, namespaceTable, jobsTable, verbose, stdout)
+	descOk, err := ExamineDescriptors(ctx, descTable, namespaceTable, jobsTable, verbose, stdout, opts...)
 	if err != nil {
 		return false, err
 	}
@@ -125,7 +133,15 @@ func Examine(
 	return descOk && jobsOk, nil
 }
 
-// ExamineDescriptors runs a suite of checks over the descriptor table.
+// ExamineDescriptors runs a suite of checks over the descriptor table. It
+// returns true if no errors were found: warnings are reported, but don't
+// affect the result. Draining names are only checked if jobsTable is not nil.
+//
+// In verbose mode, every problem found is written on its own line, and every
+// descriptor and namespace entry examined is also written as processed. In
+// non-verbose mode, only the descriptors and namespace entries with problems
+// are written, one line each giving the first of their problems and the number
+// of the others, and the summary ends with the number of problems elided.
 func ExamineDescriptors(
 	ctx context.Context,
 	descTable DescriptorTable,
@@ -133,91 +149,431 @@ func ExamineDescriptors(
 	jobsTable JobsTable,
 	verbose bool,
 	stdout io.Writer,
+	opts ...ExamineOption,
+) (ok bool, err error) {
+	e := newExamination(opts)
+	fmt.Fprintf(
+		stdout, "Examining %d descriptors and %d namespace entries%s...\n",
+		len(descTable), len(namespaceTable), e.restriction())
+	err = examineDescriptors(
+		ctx, e, descTable, namespaceTable, jobsTable, schemaChangingDescriptors(jobsTable))
+	if verbose {
+		e.writeText(stdout, verbose)
+	} else {
+		e.writeBriefText(stdout)
+	}
+	e.writeSummary(stdout)
+	if err != nil {
+		return false, err
+	}
+	return !e.hasErrors(), nil
+}
+
+// ExamineDescriptor runs the same suite of checks as ExamineDescriptors, but
+// only for the descriptor with the given ID and the namespace entries pointing
+// to it. The other descriptors are only used to check the references to and
+// from this one. Job references and draining names are not checked.
+func ExamineDescriptor(
+	ctx context.Context,
+	id descpb.ID,
+	descTable DescriptorTable,
+	namespaceTable NamespaceTable,
+	stdout io.Writer,
+	opts ...ExamineOption,
 ) (ok bool, err error) {
+	e := newExamination(opts)
 	fmt.Fprintf(
-		stdout, "Examining %d descriptors and %d namespace entries...\n",
-		len(descTable), len(namespaceTable))
-	ddg, err := newDescGetter(ctx, stdout, descTable, namespaceTable)
+		stdout, "Examining descriptor %d among %d descriptors and %d namespace entries%s...\n",
+		id, len(descTable), len(namespaceTable), e.restriction())
+	e.only = id
+	err = examineDescriptors(ctx, e, descTable, namespaceTable, nil /* jmg */, nil /* schemaChanging */)
+	e.writeText(stdout, false /* verbose */)
+	e.writeSummary(stdout)
+	if err != nil {
+		return false, err
+	}
+	if len(e.processed) == 0 {
+		return false, errors.Newf("descriptor %d not found in descriptor or namespace table", id)
+	}
+	return !e.hasErrors(), nil
+}
+
+// ExamineJSON runs the same suite of checks over the descriptor table as
+// ExamineDescriptors but writes one JSON object per line for each problem
+// found, instead of human-readable text. In verbose mode, an object is also
+// written for each descriptor and namespace entry processed, with the message
+// "processed".
+func ExamineJSON(
+	ctx context.Context,
+	descTable DescriptorTable,
+	namespaceTable NamespaceTable,
+	jobsTable JobsTable,
+	verbose bool,
+	w io.Writer,
+	opts ...ExamineOption,
+) (ok bool, err error) {
+	e := newExamination(opts)
+	err = examineDescriptors(
+		ctx, e, descTable, namespaceTable, jobsTable, schemaChangingDescriptors(jobsTable))
+	e.writeJSON(w, verbose)
 	if err != nil {
 		return false, err
 	}
-	var problemsFound bool
+	return !e.hasErrors(), nil
+}
+
+// DescriptorProblems runs the same suite of checks over the descriptor table
+// as ExamineDescriptors and returns the problems found.
+func DescriptorProblems(
+	ctx context.Context,
+	descTable DescriptorTable,
+	namespaceTable NamespaceTable,
+	jobsTable JobsTable,
+	opts ...ExamineOption,
+) ([]Problem, error) {
+	e := newExamination(opts)
+	err := examineDescriptors(
+		ctx, e, descTable, namespaceTable, jobsTable, schemaChangingDescriptors(jobsTable))
+	return e.problems, err
+}
+
+// examineDescriptors runs the descriptor checks, recording the results in e.
+// Job references are only checked if jmg is not nil, and draining names only
+// if schemaChanging, the set of IDs of the descriptors which unfinished schema
+// change jobs work on, is not nil. Whatever problems were found before any
+// error are recorded nonetheless.
+func examineDescriptors(
+	ctx context.Context,
+	e *examination,
+	descTable DescriptorTable,
+	namespaceTable NamespaceTable,
+	jmg jobs.JobMetadataGetter,
+	schemaChanging map[descpb.ID]struct{},
+) error {
+	ddg, err := newDescGetter(ctx, e, descTable, namespaceTable)
+	if err != nil {
+		return err
+	}
+
+	// Check for duplicate IDs upfront, the descriptors involved can't be
+	// examined meaningfully as only one of them is in the descGetter.
+	duplicateIDs, err := checkDuplicateIDs(e, descTable)
+	if err != nil {
+		return err
+	}
 
-	for _, row := range descTable {
+	// Determine which descriptors to examine. The problems found in each of
+	// them are recorded separately and merged afterwards in the order of the
+	// descriptor table, which keeps the output deterministic.
+	descs := make([]catalog.Descriptor, len(descTable))
+	results := make([]examination, len(descTable))
+	for i, row := range descTable {
+		results[i].partial = e.partial
+		if _, ok := duplicateIDs[row.ID]; ok {
+			continue
+		}
 		desc, ok := ddg.Descriptors[descpb.ID(row.ID)]
 		if !ok {
 			// This should never happen as ids are parsed and inserted from descTable.
 			log.Fatalf(ctx, "Descriptor id %d not found", row.ID)
 		}
-
-		if int64(desc.GetID()) != row.ID {
-			descReport(stdout, desc, "different id in descriptor table: %d", row.ID)
-			problemsFound = true
+		if !e.inScope(descpb.ID(row.ID), desc) {
 			continue
 		}
-		ve := catalog.ValidateWithRecover(ctx, ddg, catalog.ValidationLevelAllPreTxnCommit, desc)
-		for _, err := range ve.Errors() {
-			problemsFound = true
-			descReport(stdout, desc, "%s", err)
+		if !checkDescriptorID(&results[i], desc, row) {
+			e.numSkipped++
+			continue
 		}
+		descs[i] = desc
+	}
 
-		jobs.ValidateJobReferencesInDescriptor(desc, jobsTable, func(err error) {
-			problemsFound = true
-			descReport(stdout, desc, "%s", err)
-		})
+	if e.sizes != nil {
+		for _, row := range descTable {
+			desc, ok := ddg.Descriptors[descpb.ID(row.ID)]
+			if ok && e.inScope(descpb.ID(row.ID), desc) {
+				e.sizes.add(descriptorKind(desc), descpb.ID(row.ID), len(row.DescBytes))
+			}
+		}
+	}
 
-		if verbose {
-			descReport(stdout, desc, "processed")
+	// Index the namespace entries by ID, to reconcile them with the descriptors.
+	nsByID := make(map[descpb.ID][]NamespaceTableRow)
+	for _, row := range namespaceTable {
+		nsByID[descpb.ID(row.ID)] = append(nsByID[descpb.ID(row.ID)], row)
+	}
+
+	// IDs are only reserved within a cluster, whose descriptors always include
+	// the system database, and not within a partial set of descriptors.
+	idChecker := bootstrap.BootstrappedSystemIDChecker()
+	_, checkReservedIDs := ddg.Descriptors[keys.SystemDatabaseID].(catalog.DatabaseDescriptor)
+	var systemTables map[descpb.ID]catalog.TableDescriptor
+	if e.checkSystemSchema {
+		systemTables = bootstrapSystemTables()
+	}
+
+	// Partial examinations have no namespace table to validate against.
+	validationLevel := catalog.ValidationLevelAllPreTxnCommit
+	if e.partial {
+		validationLevel = catalog.ValidationLevelCrossReferences
+	}
+
+	// Examine the descriptors in parallel, first each on its own and then,
+	// once that's done for all of them, their references to one another.
+	checkFKs := make([]bool, len(descs))
+	if err := forEachInParallel(ctx, len(descs), func(ctx context.Context, i int) {
+		desc := descs[i]
+		if desc == nil {
+			return
+		}
+		ve := catalog.ValidateWithRecover(ctx, ddg, validationLevel, desc)
+		for _, err := range ve.Errors() {
+			if errors.Is(err, catalog.ErrDescriptorNotFound) {
+				results[i].missingReport(desc, ValidationFailure, "%s", err)
+			} else {
+				results[i].descReport(desc, ValidationFailure, "%s", err)
+			}
+		}
+		// Validation only checks the foreign keys of the descriptors which are
+		// valid on their own, those of the others are left to the doctor.
+		checkFKs[i] = len(ve.Errors()) == 0 ||
+			len(catalog.ValidateWithRecover(ctx, ddg, catalog.ValidationLevelSelfOnly, desc).Errors()) > 0
+		if jmg != nil {
+			jobs.ValidateJobReferencesInDescriptor(desc, jmg, func(err error) {
+				results[i].descReport(desc, InvalidJobReference, "%s", err)
+			})
+		}
+		checkDescriptor(&results[i], desc, len(ve.Errors()) == 0)
+		if !e.partial {
+			checkNamespaceEntries(&results[i], desc, nsByID[desc.GetID()])
+		}
+		if schemaChanging != nil {
+			checkDrainingNames(&results[i], desc, nsByID[desc.GetID()], schemaChanging)
+		}
+		checkPrivileges(&results[i], desc, descTable[i].DescBytes)
+		checkStoredTable(&results[i], desc, descTable[i].DescBytes)
+		if checkReservedIDs {
+			checkReservedID(&results[i], desc, idChecker)
+		}
+		if !e.versionCheckNow.IsEmpty() {
+			checkVersion(&results[i], desc, e.versionCheckNow)
+		}
+		if expected, ok := systemTables[desc.GetID()]; ok {
+			checkSystemTable(&results[i], desc, expected)
+		}
+	}); err != nil {
+		return err
+	}
+	if err := forEachInParallel(ctx, len(descs), func(ctx context.Context, i int) {
+		if descs[i] != nil {
+			checkReferences(&results[i], ddg, descs[i], checkFKs[i])
+		}
+	}); err != nil {
+		return err
+	}
+	for i := range results {
+		for _, p := range results[i].problems {
+			e.add(p)
+		}
+		if descs[i] != nil {
+			e.descProcessed(descs[i])
 		}
 	}
 
 	for _, row := range namespaceTable {
 		desc := ddg.Descriptors[descpb.ID(row.ID)]
-		err := validateNamespaceRow(row, desc)
+		if !e.inScope(descpb.ID(row.ID), desc) {
+			continue
+		}
+		err := validateNamespaceRow(ddg, row)
 		if err != nil {
-			nsReport(stdout, row, err.Error())
-			problemsFound = true
-		} else if verbose {
-			nsReport(stdout, row, "processed")
+			e.nsReport(row, InvalidNamespaceEntry, "%s", err)
 		}
+		e.nsProcessed(row, err != nil /* invalid */)
 	}
 
-	return !problemsFound, err
+	return nil
 }
 
-func validateNamespaceRow(row NamespaceTableRow, desc catalog.Descriptor) error {
-	id := descpb.ID(row.ID)
-	if id == keys.PublicSchemaID {
-		// The public schema doesn't have a descriptor.
+// forEachInParallel calls fn for every i in [0, n), using up to GOMAXPROCS
+// workers.
+func forEachInParallel(ctx context.Context, n int, fn func(ctx context.Context, i int)) error {
+	numWorkers := runtime.GOMAXPROCS(0)
+	if numWorkers > n {
+		numWorkers = n
+	}
+	var next int64
+	return ctxgroup.GroupWorkers(ctx, numWorkers, func(ctx context.Context, _ int) error {
+		for i := int(atomic.AddInt64(&next, 1) - 1); i < n; i = int(atomic.AddInt64(&next, 1) - 1) {
+			fn(ctx, i)
+		}
 		return nil
+	})
+}
+
+// schemaChangingDescriptors returns the set of IDs of the descriptors which
+// unfinished schema change jobs in jobsTable are working on, or nil if there
+// is no jobs table to tell draining names apart from stale ones with.
+func schemaChangingDescriptors(jobsTable JobsTable) map[descpb.ID]struct{} {
+	if jobsTable == nil {
+		return nil
+	}
+	ids := make(map[descpb.ID]struct{})
+	for _, md := range jobsTable {
+		if md.Status.Terminal() || md.Payload == nil {
+			continue
+		}
+		switch md.Payload.Type() {
+		case jobspb.TypeSchemaChange, jobspb.TypeTypeSchemaChange:
+			for _, id := range md.Payload.DescriptorIDs {
+				ids[id] = struct{}{}
+			}
+		}
+	}
+	return ids
+}
+
+// checkDuplicateIDs reports every row in the descriptor table whose ID is
+// shared with another row, and returns the set of such IDs.
+func checkDuplicateIDs(e *examination, descRows []DescriptorTableRow) (map[int64]struct{}, error) {
+	counts := make(map[int64]int, len(descRows))
+	for _, r := range descRows {
+		counts[r.ID]++
+	}
+	duplicateIDs := make(map[int64]struct{})
+	for _, r := range descRows {
+		if counts[r.ID] < 2 {
+			continue
+		}
+		duplicateIDs[r.ID] = struct{}{}
+		var d descpb.Descriptor
+		if err := protoutil.Unmarshal(r.DescBytes, &d); err != nil {
+			return duplicateIDs, errors.Wrapf(err, "failed to unmarshal descriptor %d", r.ID)
+		}
+		b := catalogkv.NewBuilderWithMVCCTimestamp(&d, r.ModTime)
+		if b == nil {
+			continue
+		}
+		if desc := b.BuildImmutable(); e.inScope(descpb.ID(r.ID), desc) {
+			e.descReport(desc, DuplicateDescriptorID, "duplicate descriptor ID %d", r.ID)
+			e.numSkipped++
+		}
 	}
+	return duplicateIDs, nil
+}
+
+// checkDescriptorID reports desc, whatever its kind, if its ID differs from the
+// ID of its row in the descriptor table, and returns whether they match. A
+// descriptor which doesn't match its row can't be examined meaningfully.
+func checkDescriptorID(e *examination, desc catalog.Descriptor, row DescriptorTableRow) bool {
+	if int64(desc.GetID()) == row.ID {
+		return true
+	}
+	e.descReport(desc, DescriptorIDMismatch, "different id in descriptor table: %d", row.ID)
+	return false
+}
+
+func validateNamespaceRow(ddg catalog.MapDescGetter, row NamespaceTableRow) error {
+	id := descpb.ID(row.ID)
 	isSchema := row.ParentID != keys.RootNamespaceID && row.ParentSchemaID == keys.RootNamespaceID
+	if id == keys.PublicSchemaID {
+		// The public schema doesn't have a descriptor, but every database has
+		// an entry for it.
+		if !isSchema || row.Name != tree.PublicSchema {
+			return errors.Newf("refers to the public schema, whose entries must be named %q "+
+				"and have a database as their parent", tree.PublicSchema)
+		}
+		return validateSchemaRowParent(ddg, row, "public schema")
+	}
 	if isSchema && strings.HasPrefix(row.Name, "pg_temp_") {
-		// Temporary schemas have namespace entries but not descriptors.
-		return nil
+		// Temporary schemas have namespace entries but not descriptors. Their
+		// IDs are allocated like descriptor IDs, so no descriptor may have one.
+		if desc, ok := ddg.Descriptors[id]; ok {
+			return errors.Newf("temporary schema has the ID of %s", descSubject(desc))
+		}
+		return validateSchemaRowParent(ddg, row, "temporary schema")
 	}
 	if id == descpb.InvalidID {
 		return errors.New("invalid descriptor ID")
 	}
+	desc := ddg.Descriptors[id]
 	if desc == nil {
 		return catalog.ErrDescriptorNotFound
 	}
-	for _, dn := range desc.GetDrainingNames() {
-		if dn == row.NameInfo {
-			return nil
-		}
+	if isDrainingName(desc, row.NameInfo) {
+		return nil
 	}
 	if desc.Dropped() {
 		return errors.Newf("no matching name info in draining names of dropped %s",
 			desc.DescriptorType())
 	}
-	if row.ParentID == desc.GetParentID() &&
-		row.ParentSchemaID == desc.GetParentSchemaID() &&
-		row.Name == desc.GetName() {
-		return nil
+	// The shape of the entry's key determines what kind of descriptor it may
+	// refer to.
+	var ok bool
+	var expected string
+	switch {
+	case row.ParentID == keys.RootNamespaceID && row.ParentSchemaID == keys.RootNamespaceID:
+		_, ok = desc.(catalog.DatabaseDescriptor)
+		expected = "database"
+	case isSchema:
+		_, ok = desc.(catalog.SchemaDescriptor)
+		expected = "schema"
+	default:
+		switch desc.(type) {
+		case catalog.TableDescriptor, catalog.TypeDescriptor:
+			ok = true
+		}
+		expected = "relation or type"
+	}
+	if !ok {
+		return errors.Newf("refers to %s %q instead of a %s",
+			desc.DescriptorType(), desc.GetName(), expected)
+	}
+	if row.ParentID != desc.GetParentID() || row.ParentSchemaID != desc.GetParentSchemaID() {
+		return errors.Newf("no matching name info found in non-dropped %s %q",
+			desc.DescriptorType(), desc.GetName())
+	}
+	if row.Name != desc.GetName() {
+		if diff := nameDifference(row.Name, desc.GetName()); diff != "" {
+			return errors.Newf("name %q doesn't match name %q of %s: names differ only in %s",
+				row.Name, desc.GetName(), desc.DescriptorType(), diff)
+		}
+		return errors.Newf("name %q doesn't match name %q of %s",
+			row.Name, desc.GetName(), desc.DescriptorType())
+	}
+	return nil
+}
+
+// validateSchemaRowParent checks that the parent of the namespace entry of a
+// public or temporary schema, which has no descriptor to check it against, is
+// an existing database.
+func validateSchemaRowParent(
+	ddg catalog.MapDescGetter, row NamespaceTableRow, schema string,
+) error {
+	parent, ok := ddg.Descriptors[row.ParentID]
+	if !ok {
+		return errors.Newf("%s belongs to missing database %d", schema, row.ParentID)
+	}
+	if _, ok := parent.(catalog.DatabaseDescriptor); !ok {
+		return errors.Newf("%s belongs to %s, which is not a database", schema, descSubject(parent))
+	}
+	return nil
+}
+
+// nameDifference describes how two unequal names differ if it's in a way
+// which is easily overlooked, namely in case or surrounding whitespace. It
+// returns the empty string otherwise.
+func nameDifference(a, b string) string {
+	trimmedA, trimmedB := strings.TrimSpace(a), strings.TrimSpace(b)
+	switch {
+	case strings.EqualFold(a, b):
+		return "case"
+	case trimmedA == trimmedB:
+		return "surrounding whitespace"
+	case strings.EqualFold(trimmedA, trimmedB):
+		return "case and surrounding whitespace"
+	default:
+		return ""
 	}
-	return errors.Newf("no matching name info found in non-dropped %s %q",
-		desc.DescriptorType(), desc.GetName())
 }
 
 // ExamineJobs runs a suite of consistency checks over the system.jobs table.
@@ -229,7 +585,9 @@ func ExamineJobs(
 	stdout io.Writer,
 ) (ok bool, err error) {
 	fmt.Fprintf(stdout, "Examining %d jobs...\n", len(jobsTable))
-	ddg, err := newDescGetter(ctx, stdout, descTable, nil)
+	e := &examination{}
+	ddg, err := newDescGetter(ctx, e, descTable, nil)
+	e.writeText(stdout, false /* verbose */)
 	if err != nil {
 		return false, err
 	}
@@ -246,31 +604,16 @@ func ExamineJobs(
 	return !problemsFound, nil
 }
 
-func nsReport(stdout io.Writer, row NamespaceTableRow, format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	_, _ = fmt.Fprintf(stdout, "  ParentID %3d, ParentSchemaID %2d: namespace entry %q (%d): %s\n",
-		row.ParentID, row.ParentSchemaID, row.Name, row.ID, msg)
-}
-
-func descReport(stdout io.Writer, desc catalog.Descriptor, format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	// Add descriptor-identifying prefix if it isn't there already.
-	// The prefix has the same format as the validation error wrapper.
-	msgPrefix := fmt.Sprintf("%s %q (%d): ", desc.DescriptorType(), desc.GetName(), desc.GetID())
-	if strings.HasPrefix(msg, msgPrefix) {
-		msgPrefix = ""
-	}
-	_, _ = fmt.Fprintf(stdout, "  ParentID %3d, ParentSchemaID %2d: %s%s\n",
-		desc.GetParentID(), desc.GetParentSchemaID(), msgPrefix, msg)
-}
-
 // DumpSQL dumps SQL statements to an io.Writer to load the descriptor and
 // namespace table contents into an empty cluster. System tables are not
 // included. The descriptors themselves are as they were in the source cluster,
 // with the possible exception of the version counter and the modification time
 // timestamp.
 func DumpSQL(out io.Writer, descTable DescriptorTable, namespaceTable NamespaceTable) error {
+	// IDs are only reserved within a cluster, whose descriptors always include
+	// the system database, and not within a partial set of descriptors.
 	idChecker := bootstrap.BootstrappedSystemIDChecker()
+	_, checkReservedIDs := ddg.Descriptors[keys.SystemDatabaseID].(catalog.DatabaseDescriptor)
 	minUserDescID := keys.MinUserDescriptorID(idChecker)
 	minUserCreatedDescID := catalogkeys.MinNonDefaultUserDescriptorID(idChecker)
 	// Print first transaction, which removes all predefined user descriptors.
@@ -15,6 +15,7 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"runtime"
 	"testing"
 
 	"github.com/cockroachdb/cockroach/pkg/jobs"
@@ -65,7 +66,7 @@ var validTableDesc = &descpb.Descriptor{
 	},
 }
 
-func toBytes(t *testing.T, desc *descpb.Descriptor) []byte {
+func toBytes(t testing.TB, desc *descpb.Descriptor) []byte {
 	table, database, typ, schema := descpb.FromDescriptor(desc)
 	if table != nil {
 		parentSchemaID := table.GetUnexposedParentSchemaID()
@@ -166,6 +167,8 @@ func TestExamineDescriptors(t *testing.T) {
 			},
 			expected: `Examining 1 descriptors and 0 namespace entries...
   ParentID   0, ParentSchemaID 29: relation "" (2): different id in descriptor table: 1
+Found 1 problem: 1 descriptor ID mismatch
+Examined 0 descriptors and 0 namespace entries (1 skipped: duplicate or mismatched IDs).
 `,
 		},
 		{ // 4
@@ -178,8 +181,10 @@ func TestExamineDescriptors(t *testing.T) {
 				},
 			},
 			expected: `Examining 1 descriptors and 0 namespace entries...
-  ParentID   0, ParentSchemaID 29: relation "foo" (1): invalid parent ID 0
-  ParentID   0, ParentSchemaID 29: relation "foo" (1): table must contain at least 1 column
+  ParentID   0, ParentSchemaID 29: relation "foo" (1): invalid parent ID 0 (and 1 more problem)
+Found 2 problems: 2 validation failure
+Examined 1 descriptors and 0 namespace entries.
+1 problem elided, run with --verbose for per-row detail.
 `,
 		},
 		{ // 5
@@ -195,8 +200,10 @@ func TestExamineDescriptors(t *testing.T) {
 				{NameInfo: descpb.NameInfo{ParentSchemaID: 29, Name: "foo"}, ID: 1},
 			},
 			expected: `Examining 1 descriptors and 1 namespace entries...
-  ParentID   0, ParentSchemaID 29: relation "foo" (1): invalid parent ID 0
-  ParentID   0, ParentSchemaID 29: relation "foo" (1): table must contain at least 1 column
+  ParentID   0, ParentSchemaID 29: relation "foo" (1): invalid parent ID 0 (and 1 more problem)
+Found 2 problems: 2 validation failure
+Examined 1 descriptors and 1 namespace entries.
+1 problem elided, run with --verbose for per-row detail.
 `,
 		},
 		{ // 6
@@ -210,6 +217,8 @@ func TestExamineDescriptors(t *testing.T) {
 			},
 			expected: `Examining 1 descriptors and 0 namespace entries...
   ParentID   0, ParentSchemaID  0: database "db" (1): expected matching namespace entry, found none
+Found 1 problem: 1 validation failure
+Examined 1 descriptors and 0 namespace entries.
 `,
 		},
 		{ // 7
@@ -229,6 +238,8 @@ func TestExamineDescriptors(t *testing.T) {
 			expected: `Examining 2 descriptors and 2 namespace entries...
   ParentID  52, ParentSchemaID 29: relation "t" (51): expected matching namespace entry, found none
   ParentID   0, ParentSchemaID 29: namespace entry "t" (51): no matching name info found in non-dropped relation "t"
+Found 2 problems: 1 validation failure, 1 invalid namespace entry
+Examined 2 descriptors and 2 namespace entries.
 `,
 		},
 		{ // 8
@@ -244,7 +255,10 @@ func TestExamineDescriptors(t *testing.T) {
 				{NameInfo: descpb.NameInfo{ParentID: 2, Name: "schema"}, ID: 51},
 			},
 			expected: `Examining 1 descriptors and 1 namespace entries...
-  ParentID   2, ParentSchemaID  0: schema "schema" (51): referenced database ID 2: descriptor not found
+  ParentID   2, ParentSchemaID  0: schema "schema" (51): referenced database ID 2: descriptor not found (and 1 more problem)
+Found 2 problems: 1 validation failure, 1 dangling schema parent
+Examined 1 descriptors and 1 namespace entries.
+1 problem elided, run with --verbose for per-row detail.
 `,
 		},
 		{ // 9
@@ -260,8 +274,10 @@ func TestExamineDescriptors(t *testing.T) {
 				{NameInfo: descpb.NameInfo{Name: "type"}, ID: 51},
 			},
 			expected: `Examining 1 descriptors and 1 namespace entries...
-  ParentID   0, ParentSchemaID  0: type "type" (51): invalid parentID 0
-  ParentID   0, ParentSchemaID  0: type "type" (51): invalid parent schema ID 0
+  ParentID   0, ParentSchemaID  0: type "type" (51): invalid parentID 0 (and 1 more problem)
+Found 2 problems: 2 validation failure
+Examined 1 descriptors and 1 namespace entries.
+1 problem elided, run with --verbose for per-row detail.
 `,
 		},
 		{ // 10
@@ -284,8 +300,10 @@ func TestExamineDescriptors(t *testing.T) {
 				{NameInfo: descpb.NameInfo{Name: "db"}, ID: 3},
 			},
 			expected: `Examining 2 descriptors and 2 namespace entries...
-  ParentID   3, ParentSchemaID  2: type "type" (51): referenced schema ID 2: descriptor not found
-  ParentID   3, ParentSchemaID  2: type "type" (51): arrayTypeID 0 does not exist for "ENUM": referenced type ID 0: descriptor not found
+  ParentID   3, ParentSchemaID  2: type "type" (51): referenced schema ID 2: descriptor not found (and 2 more problems)
+Found 3 problems: 2 validation failure, 1 parent schema
+Examined 2 descriptors and 2 namespace entries.
+2 problems elided, run with --verbose for per-row detail.
 `,
 		},
 		{ // 11
@@ -314,6 +332,8 @@ func TestExamineDescriptors(t *testing.T) {
 			},
 			expected: `Examining 2 descriptors and 2 namespace entries...
   ParentID  51, ParentSchemaID 29: type "type" (52): arrayTypeID 0 does not exist for "ENUM": referenced type ID 0: descriptor not found
+Found 1 problem: 1 validation failure
+Examined 2 descriptors and 2 namespace entries.
 `,
 		},
 		{ // 12
@@ -345,8 +365,11 @@ func TestExamineDescriptors(t *testing.T) {
 				{NameInfo: descpb.NameInfo{Name: "db2"}, ID: 54},
 			},
 			expected: `Examining 4 descriptors and 4 namespace entries...
-  ParentID  52, ParentSchemaID 53: relation "t" (51): parent schema 53 is in different database 54
-  ParentID  54, ParentSchemaID  0: schema "schema" (53): not present in parent database [54] schemas mapping
+  ParentID  52, ParentSchemaID 53: relation "t" (51): parent schema 53 is in different database 54 (and 1 more problem)
+  ParentID  54, ParentSchemaID  0: schema "schema" (53): not present in parent database [54] schemas mapping (and 1 more problem)
+Found 4 problems: 2 validation failure, 1 one-sided schema parent, 1 parent schema
+Examined 4 descriptors and 4 namespace entries.
+2 problems elided, run with --verbose for per-row detail.
 `,
 		},
 		{ // 13
@@ -357,7 +380,12 @@ func TestExamineDescriptors(t *testing.T) {
 				{NameInfo: descpb.NameInfo{Name: "causes_error"}, ID: 2},
 			},
 			expected: `Examining 0 descriptors and 4 namespace entries...
+  ParentID   0, ParentSchemaID  0: namespace entry "foo" (29): refers to the public schema, whose entries must be named "public" and have a database as their parent
+  ParentID   0, ParentSchemaID  0: namespace entry "bar" (29): refers to the public schema, whose entries must be named "public" and have a database as their parent
+  ParentID 123, ParentSchemaID  0: namespace entry "pg_temp_foo" (1): temporary schema belongs to missing database 123
   ParentID   0, ParentSchemaID  0: namespace entry "causes_error" (2): descriptor not found
+Found 4 problems: 4 invalid namespace entry
+Examined 0 descriptors and 4 namespace entries.
 `,
 		},
 		{ // 14
@@ -366,6 +394,8 @@ func TestExamineDescriptors(t *testing.T) {
 			},
 			expected: `Examining 0 descriptors and 1 namespace entries...
   ParentID   0, ParentSchemaID  0: namespace entry "null" (0): invalid descriptor ID
+Found 1 problem: 1 invalid namespace entry
+Examined 0 descriptors and 1 namespace entries.
 `,
 		},
 		{ // 15
@@ -427,6 +457,8 @@ func TestExamineDescriptors(t *testing.T) {
 			},
 			expected: `Examining 1 descriptors and 3 namespace entries...
   ParentID   0, ParentSchemaID  0: database "db" (1): expected matching namespace entry for draining name (0, 0, db3), found none
+Found 1 problem: 1 validation failure
+Examined 1 descriptors and 3 namespace entries.
 `,
 		},
 		{ // 18
@@ -444,7 +476,10 @@ func TestExamineDescriptors(t *testing.T) {
 				{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
 			},
 			expected: `Examining 2 descriptors and 2 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t" (51): descriptor 51 in state DROP still has namespace entry (52, 29, t), which should not exist
   ParentID  52, ParentSchemaID 29: namespace entry "t" (51): no matching name info in draining names of dropped relation
+Found 2 problems: 1 invalid namespace entry, 1 lingering namespace entry
+Examined 2 descriptors and 2 namespace entries.
 `,
 		},
 		{ // 19
@@ -468,6 +503,8 @@ func TestExamineDescriptors(t *testing.T) {
 			},
 			expected: `Examining 2 descriptors and 2 namespace entries...
   ParentID  52, ParentSchemaID 29: relation "t" (51): unimplemented: primary key dropped without subsequent addition of new primary key in same transaction
+Found 1 problem: 1 validation failure
+Examined 2 descriptors and 2 namespace entries.
 `,
 		},
 		{ // 20
@@ -501,11 +538,12 @@ func TestExamineDescriptors(t *testing.T) {
 				{NameInfo: descpb.NameInfo{ParentID: 57, ParentSchemaID: 29, Name: "c"}, ID: 60},
 			},
 			expected: `Examining 6 descriptors and 6 namespace entries...
-  ParentID  57, ParentSchemaID 29: relation "a" (58): failed to upgrade descriptor: index-id "2" does not exist
-  ParentID  57, ParentSchemaID 29: relation "b" (59): failed to upgrade descriptor: referenced table ID 52: descriptor not found
-  ParentID  57, ParentSchemaID 29: relation "a" (58): index "primary" contains deprecated foreign key representation
-  ParentID  57, ParentSchemaID 29: relation "b" (59): index "primary" contains deprecated foreign key representation
+  ParentID  57, ParentSchemaID 29: relation "a" (58): failed to upgrade descriptor: index-id "2" does not exist (and 1 more problem)
+  ParentID  57, ParentSchemaID 29: relation "b" (59): failed to upgrade descriptor: referenced table ID 52: descriptor not found (and 1 more problem)
   ParentID  57, ParentSchemaID 29: relation "c" (60): missing fk back reference "fk_i_ref_b" to "c" from "a"
+Found 5 problems: 2 upgrade failure, 3 validation failure
+Examined 6 descriptors and 6 namespace entries.
+2 problems elided, run with --verbose for per-row detail.
 `,
 		},
 		{ // 21
@@ -536,6 +574,158 @@ func TestExamineDescriptors(t *testing.T) {
 			},
 			expected: `Examining 2 descriptors and 2 namespace entries...
   ParentID  52, ParentSchemaID 29: relation "t" (51): mutation job 123 has terminal status (canceled)
+Found 1 problem: 1 invalid job reference
+Examined 2 descriptors and 2 namespace entries.
+`,
+		},
+		{ // 22
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, func() *descpb.Descriptor {
+					desc := protoutil.Clone(validTableDesc).(*descpb.Descriptor)
+					tbl, _, _, _ := descpb.FromDescriptor(desc)
+					tbl.PrimaryIndex.KeyColumnIDs = []descpb.ColumnID{2}
+					return desc
+				}())},
+				{
+					ID: 52,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+						Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+					}}),
+				},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+				{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+			},
+			expected: `Examining 2 descriptors and 2 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t" (51): index "t_pkey" column "col" should have ID 1, but found ID 2
+Found 1 problem: 1 validation failure
+Examined 2 descriptors and 2 namespace entries.
+`,
+		},
+		{ // 23
+			descTable: doctor.DescriptorTable{
+				{
+					ID: 1,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+						Database: &descpb.DatabaseDescriptor{Name: "db", ID: 1},
+					}}),
+				},
+				{
+					ID: 1,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+						Database: &descpb.DatabaseDescriptor{Name: "db2", ID: 1},
+					}}),
+				},
+			},
+			expected: `Examining 2 descriptors and 0 namespace entries...
+  ParentID   0, ParentSchemaID  0: database "db" (1): duplicate descriptor ID 1
+  ParentID   0, ParentSchemaID  0: database "db2" (1): duplicate descriptor ID 1
+Found 2 problems: 2 duplicate descriptor ID
+Examined 0 descriptors and 0 namespace entries (2 skipped: duplicate or mismatched IDs).
+`,
+		},
+		{ // 24
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+				{
+					ID: 52,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+						Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+					}}),
+				},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+				{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t2"}, ID: 51},
+				{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+			},
+			expected: `Examining 2 descriptors and 3 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t" (51): referenced by 2 namespace entries: (52, 29, t), (52, 29, t2)
+  ParentID  52, ParentSchemaID 29: namespace entry "t2" (51): name "t2" doesn't match name "t" of relation
+Found 2 problems: 1 invalid namespace entry, 1 duplicate namespace entry
+Examined 2 descriptors and 3 namespace entries.
+`,
+		},
+		{ // 25
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+				{
+					ID: 52,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+						Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+					}}),
+				},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				{NameInfo: descpb.NameInfo{Name: "t"}, ID: 51},
+				{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+			},
+			expected: `Examining 2 descriptors and 2 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t" (51): expected matching namespace entry, found none
+  ParentID   0, ParentSchemaID  0: namespace entry "t" (51): refers to relation "t" instead of a database
+Found 2 problems: 1 validation failure, 1 invalid namespace entry
+Examined 2 descriptors and 2 namespace entries.
+`,
+		},
+		{ // 26
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+				{
+					ID: 52,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+						Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+					}}),
+				},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "T "}, ID: 51},
+				{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+			},
+			expected: `Examining 2 descriptors and 2 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t" (51): expected matching namespace entry, found none
+  ParentID  52, ParentSchemaID 29: namespace entry "T " (51): name "T " doesn't match name "t" of relation: names differ only in case and surrounding whitespace
+Found 2 problems: 1 validation failure, 1 invalid namespace entry
+Examined 2 descriptors and 2 namespace entries.
+`,
+		},
+		{ // 27
+			descTable: doctor.DescriptorTable{
+				{
+					ID: 51,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+						Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+					}}),
+				},
+				{
+					ID: 53,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Schema{
+						Schema: &descpb.SchemaDescriptor{Name: "sc", ID: 54, ParentID: 51},
+					}}),
+				},
+				{
+					ID: 55,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Type{
+						Type: &descpb.TypeDescriptor{Name: "typ", ID: 56, ParentID: 51, ParentSchemaID: keys.PublicSchemaID},
+					}}),
+				},
+				{
+					ID: 57,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Table{
+						Table: &descpb.TableDescriptor{
+							Name: "seq", ID: 58, ParentID: 51, UnexposedParentSchemaID: keys.PublicSchemaID,
+							SequenceOpts: &descpb.TableDescriptor_SequenceOpts{Increment: 1},
+						},
+					}}),
+				},
+			},
+			expected: `Examining 4 descriptors and 0 namespace entries...
+  ParentID   0, ParentSchemaID  0: database "db" (52): different id in descriptor table: 51
+  ParentID  51, ParentSchemaID  0: schema "sc" (54): different id in descriptor table: 53
+  ParentID  51, ParentSchemaID 29: type "typ" (56): different id in descriptor table: 55
+  ParentID  51, ParentSchemaID 29: relation "seq" (58): different id in descriptor table: 57
+Found 4 problems: 4 descriptor ID mismatch
+Examined 0 descriptors and 0 namespace entries (4 skipped: duplicate or mismatched IDs).
 `,
 		},
 	}
@@ -552,9 +742,720 @@ func TestExamineDescriptors(t *testing.T) {
 		}
 		require.Equalf(t, test.valid, valid, msg)
 		require.Equalf(t, test.expected, buf.String(), msg)
+
+		// The JSON output comes from the same checks.
+		buf.Reset()
+		valid, err = doctor.ExamineJSON(
+			context.Background(), test.descTable, test.namespaceTable, test.jobsTable, false, &buf)
+		if test.errStr != "" {
+			require.Containsf(t, err.Error(), test.errStr, msg)
+		} else {
+			require.NoErrorf(t, err, msg)
+		}
+		require.Equalf(t, test.valid, valid, msg)
+	}
+}
+
+func TestExamineDescriptor(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	brokenTableDesc := func(name string, id descpb.ID) *descpb.Descriptor {
+		return modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.Name = name
+			tbl.ID = id
+			tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName(name)
+			tbl.PrimaryIndex.KeyColumnIDs = []descpb.ColumnID{2}
+		})
+	}
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, brokenTableDesc("t", 51))},
+		{
+			ID: 52,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+				Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+			}}),
+		},
+		{ID: 53, DescBytes: toBytes(t, brokenTableDesc("u", 53))},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+		{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "u"}, ID: 53},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "gone"}, ID: 54},
+	}
+
+	tests := []struct {
+		id       descpb.ID
+		valid    bool
+		errStr   string
+		expected string
+	}{
+		{
+			id: 51,
+			expected: `Examining descriptor 51 among 3 descriptors and 4 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t" (51): index "t_pkey" column "col" should have ID 1, but found ID 2
+Found 1 problem: 1 validation failure
+Examined 1 descriptors and 1 namespace entries.
+`,
+		},
+		{
+			id:    52,
+			valid: true,
+			expected: `Examining descriptor 52 among 3 descriptors and 4 namespace entries...
+`,
+		},
+		{
+			id: 54,
+			expected: `Examining descriptor 54 among 3 descriptors and 4 namespace entries...
+  ParentID  52, ParentSchemaID 29: namespace entry "gone" (54): descriptor not found
+Found 1 problem: 1 invalid namespace entry
+Examined 0 descriptors and 1 namespace entries.
+`,
+		},
+		{
+			id:     55,
+			errStr: "descriptor 55 not found",
+			expected: `Examining descriptor 55 among 3 descriptors and 4 namespace entries...
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d", test.id), func(t *testing.T) {
+			var buf bytes.Buffer
+			valid, err := doctor.ExamineDescriptor(
+				context.Background(), test.id, descTable, namespaceTable, &buf)
+			if test.errStr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), test.errStr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.valid, valid)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestExamineDescriptorKind(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.PrimaryIndex.KeyColumnIDs = []descpb.ColumnID{2}
+		}))},
+		{
+			ID: 52,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+				Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+			}}),
+		},
+		{
+			ID: 54,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Type{
+				Type: &descpb.TypeDescriptor{Name: "typ", ID: 54, Kind: descpb.TypeDescriptor_ENUM},
+			}}),
+		},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+		{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "gone"}, ID: 55},
+	}
+
+	tests := []struct {
+		kind     doctor.DescriptorKind
+		expected []string
+	}{
+		{kind: doctor.DescriptorKindType, expected: []string{`type "typ" (54)`}},
+		{kind: doctor.DescriptorKindTable, expected: []string{`relation "t" (51)`}},
+		{kind: doctor.DescriptorKindSequence},
+	}
+
+	for _, test := range tests {
+		t.Run(test.kind.String(), func(t *testing.T) {
+			problems, err := doctor.DescriptorProblems(
+				context.Background(), descTable, namespaceTable, nil, /* jobsTable */
+				doctor.WithDescriptorKind(test.kind))
+			require.NoError(t, err)
+			var actual []string
+			for _, p := range problems {
+				if s := p.Subject.String(); len(actual) == 0 || actual[len(actual)-1] != s {
+					actual = append(actual, s)
+				}
+			}
+			require.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestExamineModifiedSince(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	brokenTableDesc := func(name string, id descpb.ID) *descpb.Descriptor {
+		return modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.Name = name
+			tbl.ID = id
+			tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName(name)
+			tbl.PrimaryIndex.KeyColumnIDs = []descpb.ColumnID{2}
+		})
+	}
+	descTable := doctor.DescriptorTable{
+		{
+			ID:        51,
+			DescBytes: toBytes(t, brokenTableDesc("t", 51)),
+			ModTime:   hlc.Timestamp{WallTime: 1e9},
+		},
+		{
+			ID: 52,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+				Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+			}}),
+			ModTime: hlc.Timestamp{WallTime: 1e9},
+		},
+		{
+			ID:        53,
+			DescBytes: toBytes(t, brokenTableDesc("u", 53)),
+			ModTime:   hlc.Timestamp{WallTime: 3e9},
+		},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+		{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "u"}, ID: 53},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "gone"}, ID: 54},
+	}
+
+	var buf bytes.Buffer
+	valid, err := doctor.ExamineDescriptors(
+		context.Background(), descTable, namespaceTable, nil /* jobsTable */, false, &buf,
+		doctor.WithModifiedSince(hlc.Timestamp{WallTime: 3e9}))
+	require.NoError(t, err)
+	require.False(t, valid)
+	require.Equal(t, `Examining 3 descriptors and 4 namespace entries, restricted to the descriptors modified since 3.000000000,0...
+  ParentID  52, ParentSchemaID 29: relation "u" (53): index "u_pkey" column "col" should have ID 1, but found ID 2
+Found 1 problem: 1 validation failure
+Examined 1 descriptors and 1 namespace entries.
+`, buf.String())
+}
+
+// syntheticDescriptors returns a database with n tables, every third of which
+// has an index referencing a missing column.
+func syntheticDescriptors(
+	t testing.TB, n int,
+) (doctor.DescriptorTable, doctor.NamespaceTable) {
+	descTable := doctor.DescriptorTable{{
+		ID: 52,
+		DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+			Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+		}}),
+	}}
+	namespaceTable := doctor.NamespaceTable{{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52}}
+	for i := 0; i < n; i++ {
+		id := descpb.ID(100 + i)
+		name := fmt.Sprintf("t%d", i)
+		descTable = append(descTable, doctor.DescriptorTableRow{
+			ID: int64(id),
+			DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+				tbl.Name = name
+				tbl.ID = id
+				tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName(name)
+				if i%3 == 0 {
+					tbl.PrimaryIndex.KeyColumnIDs = []descpb.ColumnID{2}
+				}
+			})),
+		})
+		namespaceTable = append(namespaceTable, doctor.NamespaceTableRow{
+			NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: keys.PublicSchemaID, Name: name},
+			ID:       int64(id),
+		})
+	}
+	return descTable, namespaceTable
+}
+
+// TestExamineDescriptorsParallel checks that the output doesn't depend on the
+// number of workers examining the descriptors.
+func TestExamineDescriptorsParallel(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	descTable, namespaceTable := syntheticDescriptors(t, 300)
+	examine := func(procs int) string {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+		var buf bytes.Buffer
+		valid, err := doctor.ExamineDescriptors(
+			context.Background(), descTable, namespaceTable, nil /* jobsTable */, true /* verbose */, &buf)
+		require.NoError(t, err)
+		require.False(t, valid)
+		return buf.String()
+	}
+	expected := examine(1)
+	for _, procs := range []int{2, 8} {
+		require.Equal(t, expected, examine(procs), "GOMAXPROCS=%d", procs)
+	}
+}
+
+func BenchmarkExamineDescriptors(b *testing.B) {
+	defer log.Scope(b).Close(b)
+
+	descTable, namespaceTable := syntheticDescriptors(b, 50000)
+	for _, procs := range []int{1, runtime.NumCPU()} {
+		b.Run(fmt.Sprintf("procs=%d", procs), func(b *testing.B) {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := doctor.DescriptorProblems(
+					context.Background(), descTable, namespaceTable, nil, /* jobsTable */
+				); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestExamineVerbose checks that non-verbose text output gives one line per
+// descriptor or namespace entry with problems, whereas verbose output gives
+// every problem and every entry processed.
+func TestExamineVerbose(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.PrimaryIndex.KeyColumnIDs = []descpb.ColumnID{2}
+		}))},
+		{
+			ID: 52,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+				Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+			}}),
+		},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+		{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "gone"}, ID: 54},
+	}
+
+	tests := []struct {
+		verbose  bool
+		expected string
+	}{
+		{
+			verbose: false,
+			expected: `Examining 2 descriptors and 3 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t" (51): index "t_pkey" column "col" should have ID 1, but found ID 2
+  ParentID  52, ParentSchemaID 29: namespace entry "gone" (54): descriptor not found
+Found 2 problems: 1 validation failure, 1 invalid namespace entry
+Examined 2 descriptors and 3 namespace entries.
+`,
+		},
+		{
+			verbose: true,
+			expected: `Examining 2 descriptors and 3 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t" (51): index "t_pkey" column "col" should have ID 1, but found ID 2
+  ParentID  52, ParentSchemaID 29: relation "t" (51): processed
+  ParentID   0, ParentSchemaID  0: database "db" (52): processed
+  ParentID  52, ParentSchemaID 29: namespace entry "t" (51): processed
+  ParentID   0, ParentSchemaID  0: namespace entry "db" (52): processed
+  ParentID  52, ParentSchemaID 29: namespace entry "gone" (54): descriptor not found
+Found 2 problems: 1 validation failure, 1 invalid namespace entry
+Examined 2 descriptors and 3 namespace entries.
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("verbose=%t", test.verbose), func(t *testing.T) {
+			var buf bytes.Buffer
+			valid, err := doctor.ExamineDescriptors(
+				context.Background(), descTable, namespaceTable, nil /* jobsTable */, test.verbose, &buf)
+			require.NoError(t, err)
+			require.False(t, valid)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestExamineSeverity(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+		{
+			ID: 52,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+				Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+			}}),
+		},
+		{ID: 54, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.Name = "s"
+			tbl.ID = 54
+			tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("s")
+			tbl.SequenceOpts = &descpb.TableDescriptor_SequenceOpts{
+				Increment: 1, MinValue: 1, MaxValue: 100, Start: 1,
+				SequenceOwner: descpb.TableDescriptor_SequenceOpts_SequenceOwner{
+					OwnerTableID: 51, OwnerColumnID: 1,
+				},
+			}
+		}))},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+		{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "s"}, ID: 54},
+	}
+
+	tests := []struct {
+		name     string
+		opts     []doctor.ExamineOption
+		expected string
+	}{
+		{
+			name: "all",
+			expected: `Examining 3 descriptors and 3 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "s" (54): warning: owned by column "col" of relation "t" (51), which doesn't list it as owned
+Found 1 problem: 1 sequence ownership
+Examined 3 descriptors and 3 namespace entries.
+`,
+		},
+		{
+			name: "errors only",
+			opts: []doctor.ExamineOption{doctor.WithMinSeverity(doctor.SeverityError)},
+			expected: `Examining 3 descriptors and 3 namespace entries...
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			valid, err := doctor.ExamineDescriptors(
+				context.Background(), descTable, namespaceTable, nil /* jobsTable */, false, &buf,
+				test.opts...)
+			require.NoError(t, err)
+			// Warnings don't make the examination fail.
+			require.True(t, valid)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestExamineIgnore(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	brokenTableDesc := func(name string, id descpb.ID) *descpb.Descriptor {
+		return modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.Name = name
+			tbl.ID = id
+			tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName(name)
+			tbl.PrimaryIndex.KeyColumnIDs = []descpb.ColumnID{2}
+		})
+	}
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, brokenTableDesc("t", 51))},
+		{
+			ID: 52,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+				Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+			}}),
+		},
+		{ID: 53, DescBytes: toBytes(t, brokenTableDesc("u", 53))},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+		{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "u"}, ID: 53},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "gone"}, ID: 54},
+	}
+
+	tests := []struct {
+		ignore   []descpb.ID
+		valid    bool
+		expected string
+	}{
+		{
+			ignore: []descpb.ID{51, 54},
+			expected: `Examining 3 descriptors and 4 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "u" (53): index "u_pkey" column "col" should have ID 1, but found ID 2
+Found 1 problem: 1 validation failure
+Examined 3 descriptors and 4 namespace entries.
+2 problems suppressed by ignore-list.
+`,
+		},
+		{
+			ignore: []descpb.ID{51, 53, 54},
+			valid:  true,
+			expected: `Examining 3 descriptors and 4 namespace entries...
+3 problems suppressed by ignore-list.
+`,
+		},
+		{
+			ignore: []descpb.ID{52},
+			expected: `Examining 3 descriptors and 4 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t" (51): index "t_pkey" column "col" should have ID 1, but found ID 2
+  ParentID  52, ParentSchemaID 29: relation "u" (53): index "u_pkey" column "col" should have ID 1, but found ID 2
+  ParentID  52, ParentSchemaID 29: namespace entry "gone" (54): descriptor not found
+Found 3 problems: 2 validation failure, 1 invalid namespace entry
+Examined 3 descriptors and 4 namespace entries.
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.ignore), func(t *testing.T) {
+			var buf bytes.Buffer
+			valid, err := doctor.ExamineDescriptors(
+				context.Background(), descTable, namespaceTable, nil /* jobsTable */, false, &buf,
+				doctor.WithIgnore(test.ignore...))
+			require.NoError(t, err)
+			require.Equal(t, test.valid, valid)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestExamineDeduplication(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	orphanTableDesc := func(name string, id descpb.ID) *descpb.Descriptor {
+		return modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.Name = name
+			tbl.ID = id
+			tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName(name)
+		})
+	}
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, orphanTableDesc("t1", 51))},
+		{ID: 53, DescBytes: toBytes(t, orphanTableDesc("t2", 53))},
+		{ID: 54, DescBytes: toBytes(t, orphanTableDesc("t3", 54))},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t1"}, ID: 51},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t2"}, ID: 53},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t3"}, ID: 54},
+	}
+
+	tests := []struct {
+		maxIDs   int
+		expected string
+	}{
+		{
+			maxIDs: 3,
+			expected: `Examining 3 descriptors and 3 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t1" (51): referenced database ID 52: descriptor not found (3 problems like this one, affecting IDs 51, 53, 54)
+Found 3 problems: 3 validation failure
+Examined 3 descriptors and 3 namespace entries.
+`,
+		},
+		{
+			maxIDs: 2,
+			expected: `Examining 3 descriptors and 3 namespace entries...
+  ParentID  52, ParentSchemaID 29: relation "t1" (51): referenced database ID 52: descriptor not found (3 problems like this one, affecting IDs 51, 53, 1 more)
+Found 3 problems: 3 validation failure
+Examined 3 descriptors and 3 namespace entries.
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.maxIDs), func(t *testing.T) {
+			var buf bytes.Buffer
+			valid, err := doctor.ExamineDescriptors(
+				context.Background(), descTable, namespaceTable, nil /* jobsTable */, false, &buf,
+				doctor.WithDeduplication(test.maxIDs))
+			require.NoError(t, err)
+			require.False(t, valid)
+			require.Equal(t, test.expected, buf.String())
+		})
+	}
+}
+
+func TestExamineJSON(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	tests := []struct {
+		descTable      doctor.DescriptorTable
+		namespaceTable doctor.NamespaceTable
+		verbose        bool
+		valid          bool
+		expected       string
+	}{
+		{
+			valid:    true,
+			expected: "",
+		},
+		{
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+				{
+					ID: 52,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+						Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+					}}),
+				},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				{NameInfo: descpb.NameInfo{ParentSchemaID: 29, Name: "t"}, ID: 51},
+				{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+			},
+			expected: `{"descriptorID":51,"descriptorType":"relation","parentID":52,"parentSchemaID":29,"name":"t","kind":"validation failure","severity":"error","message":"expected matching namespace entry, found none"}
+{"descriptorID":51,"descriptorType":"namespace entry","parentID":0,"parentSchemaID":29,"name":"t","kind":"invalid namespace entry","severity":"error","message":"no matching name info found in non-dropped relation \"t\""}
+`,
+		},
+		{
+			verbose: true,
+			valid:   true,
+			descTable: doctor.DescriptorTable{
+				{
+					ID: 52,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+						Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+					}}),
+				},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+			},
+			expected: `{"descriptorID":52,"descriptorType":"database","parentID":0,"parentSchemaID":0,"name":"db","message":"processed"}
+{"descriptorID":52,"descriptorType":"namespace entry","parentID":0,"parentSchemaID":0,"name":"db","message":"processed"}
+`,
+		},
+	}
+
+	for i, test := range tests {
+		var buf bytes.Buffer
+		valid, err := doctor.ExamineJSON(
+			context.Background(), test.descTable, test.namespaceTable, nil /* jobsTable */, test.verbose, &buf)
+		msg := fmt.Sprintf("Test %d failed!", i+1)
+		require.NoErrorf(t, err, msg)
+		require.Equalf(t, test.valid, valid, msg)
+		require.Equalf(t, test.expected, buf.String(), msg)
+	}
+}
+
+func TestDescriptorProblems(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	descTable := doctor.DescriptorTable{
+		{
+			ID: 1,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Table{
+				Table: &descpb.TableDescriptor{ID: 2},
+			}}),
+		},
+		{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+		{
+			ID: 52,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+				Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+			}}),
+		},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+		{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+		{NameInfo: descpb.NameInfo{Name: "causes_error"}, ID: 3},
+	}
+	problems, err := doctor.DescriptorProblems(
+		context.Background(), descTable, namespaceTable, nil /* jobsTable */)
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+	require.Equal(t, doctor.DescriptorIDMismatch, problems[0].Kind)
+	require.Equal(t, descpb.ID(2), problems[0].DescriptorID)
+	require.Equal(t, doctor.InvalidNamespaceEntry, problems[1].Kind)
+	require.Equal(t, doctor.NamespaceEntryType, problems[1].DescriptorType)
+	require.Equal(t, descpb.ID(3), problems[1].DescriptorID)
+}
+
+// modifiedTableDesc returns a copy of validTableDesc modified by fn.
+func modifiedTableDesc(fn func(tbl *descpb.TableDescriptor)) *descpb.Descriptor {
+	desc := protoutil.Clone(validTableDesc).(*descpb.Descriptor)
+	tbl, _, _, _ := descpb.FromDescriptor(desc)
+	fn(tbl)
+	return desc
+}
+
+// checkTest is a test case for the checks performed by the doctor in addition
+// to descriptor validation.
+type checkTest struct {
+	name           string
+	descTable      doctor.DescriptorTable
+	namespaceTable doctor.NamespaceTable
+	jobsTable      doctor.JobsTable
+	opts           []doctor.ExamineOption
+	expected       []string
+}
+
+// runCheckTests runs each test through DescriptorProblems, ignoring validation
+// failures.
+func runCheckTests(t *testing.T, tests []checkTest) {
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems, err := doctor.DescriptorProblems(
+				context.Background(), test.descTable, test.namespaceTable, test.jobsTable, test.opts...)
+			require.NoError(t, err)
+			var actual []string
+			for _, p := range problems {
+				if p.Kind == doctor.ValidationFailure {
+					continue
+				}
+				actual = append(actual, fmt.Sprintf("%s: %s: %s", p.Kind, p.Subject, p.Message))
+			}
+			require.Equal(t, test.expected, actual)
+		})
 	}
 }
 
+// dbRow returns the descriptor of database "db" (52), the parent of
+// validTableDesc.
+func dbRow(t *testing.T) doctor.DescriptorTableRow {
+	return doctor.DescriptorTableRow{
+		ID: 52,
+		DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+			Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+		}}),
+	}
+}
+
+var dbNamespaceRow = doctor.NamespaceTableRow{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52}
+
+// tableNamespaceRow returns the namespace entry of a relation in the public
+// schema of database "db" (52).
+func tableNamespaceRow(name string, id int64) doctor.NamespaceTableRow {
+	return doctor.NamespaceTableRow{
+		NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: keys.PublicSchemaID, Name: name},
+		ID:       id,
+	}
+}
+
+// TestExamineChecks checks that a valid descriptor passes the checks performed
+// by the doctor in addition to descriptor validation.
+func TestExamineChecks(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "valid",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+		},
+	})
+}
+
 func TestExamineJobs(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	defer log.Scope(t).Close(t)
@@ -0,0 +1,141 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/seqexpr"
+	"github.com/cockroachdb/cockroach/pkg/sql/parser"
+	"github.com/cockroachdb/cockroach/pkg/sql/sem/tree"
+	"github.com/cockroachdb/errors"
+)
+
+// checkExpressions checks that the expressions of the table's check
+// constraints and computed columns parse, and that every column they
+// reference exists.
+func checkExpressions(e *examination, table catalog.TableDescriptor) {
+	for _, chk := range table.AllActiveAndInactiveChecks() {
+		checkExpression(e, table, "check constraint", chk.Name, chk.Expr)
+	}
+	for _, col := range table.DeletableColumns() {
+		if col.IsComputed() {
+			checkExpression(e, table, "computed column", col.GetName(), col.GetComputeExpr())
+		}
+	}
+}
+
+// checkDefaultExpressions checks that the default expression of each of the
+// table's columns parses, and that every sequence it uses by ID exists and is
+// listed among the sequences used by the column. Sequences used by name date
+// back to before sequences were referenced by ID, resolving them would
+// require the search path, so they're not checked.
+func checkDefaultExpressions(
+	e *examination, ddg catalog.MapDescGetter, table catalog.TableDescriptor,
+) {
+	if table.Dropped() {
+		return
+	}
+	for _, col := range table.DeletableColumns() {
+		if !col.HasDefault() {
+			continue
+		}
+		parsed := checkExpression(e, table, "default of column", col.GetName(), col.GetDefaultExpr())
+		if parsed == nil {
+			continue
+		}
+		seqs, err := usedSequences(parsed)
+		if err != nil {
+			e.descReport(table, InvalidExpression,
+				"default of column %q has invalid sequence usage in expression: %s: %v",
+				col.GetName(), col.GetDefaultExpr(), err)
+			continue
+		}
+		for _, seq := range seqs {
+			if !seq.IsByID() {
+				continue
+			}
+			id := descpb.ID(seq.SeqID)
+			if desc, ok := ddg.Descriptors[id]; !ok {
+				e.missingReport(table, InvalidSequenceReference,
+					"default of column %q uses missing sequence %d", col.GetName(), id)
+			} else if seqDesc, ok := desc.(catalog.TableDescriptor); !ok || !seqDesc.IsSequence() {
+				e.descReport(table, InvalidSequenceReference,
+					"default of column %q uses %s, which is not a sequence",
+					col.GetName(), descSubject(desc))
+			}
+			if !containsID(col.ColumnDesc().UsesSequenceIds, id) {
+				e.descReport(table, InvalidSequenceReference,
+					"default of column %q uses sequence %d, which the column doesn't list as used",
+					col.GetName(), id)
+			}
+		}
+	}
+}
+
+// usedSequences returns the sequences used in expr. Unexpected function
+// arguments cause a panic while looking for sequences, which is recovered
+// from and returned as an error.
+func usedSequences(expr tree.Expr) (seqs []seqexpr.SeqIdentifier, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			if err, ok = r.(error); !ok {
+				err = errors.Newf("%v", r)
+			}
+		}
+	}()
+	return seqexpr.GetUsedSequences(expr)
+}
+
+// checkExpression checks that expr, which belongs to the table element of the
+// given kind and name, parses and only references existing columns. It returns
+// the parsed expression, or nil if it doesn't parse.
+func checkExpression(
+	e *examination, table catalog.TableDescriptor, kind string, name string, expr string,
+) tree.Expr {
+	parsed, err := parser.ParseExpr(expr)
+	if err != nil {
+		e.descReport(table, InvalidExpression,
+			"%s %q has an expression which doesn't parse: %s: %v", kind, name, expr, err)
+		return nil
+	}
+	for _, colName := range unknownColumns(table, parsed) {
+		e.descReport(table, InvalidExpression,
+			"%s %q references unknown column %q in expression: %s", kind, name, colName, expr)
+	}
+	return parsed
+}
+
+// unknownColumns returns the names of the columns referenced in expr which
+// don't exist in the table, in order of appearance.
+func unknownColumns(table catalog.TableDescriptor, expr tree.Expr) []string {
+	var names []string
+	_, _ = tree.SimpleVisit(expr, func(expr tree.Expr) (recurse bool, newExpr tree.Expr, err error) {
+		vBase, ok := expr.(tree.VarName)
+		if !ok {
+			return true, expr, nil
+		}
+		v, err := vBase.NormalizeVarName()
+		if err != nil {
+			return false, expr, nil
+		}
+		c, ok := v.(*tree.ColumnItem)
+		if !ok {
+			return true, expr, nil
+		}
+		if _, err := table.FindColumnWithName(c.ColumnName); err != nil {
+			names = append(names, string(c.ColumnName))
+		}
+		return false, expr, nil
+	})
+	return names
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor_test
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+)
+
+func TestCheckExpressions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "expressions",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Checks = []*descpb.TableDescriptor_CheckConstraint{
+						{Name: "chk_parse", Expr: "col >", ColumnIDs: []descpb.ColumnID{1}},
+						{Name: "chk_columns", Expr: "col > gone AND gone2 > 0", ColumnIDs: []descpb.ColumnID{1}},
+					}
+					computeExpr := "missing + 1"
+					tbl.Columns[0].ComputeExpr = &computeExpr
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`expression: relation "t" (51): check constraint "chk_parse" has an expression which doesn't parse: col >: at or near "EOF": syntax error`,
+				`expression: relation "t" (51): check constraint "chk_columns" references unknown column "gone" in expression: col > gone AND gone2 > 0`,
+				`expression: relation "t" (51): check constraint "chk_columns" references unknown column "gone2" in expression: col > gone AND gone2 > 0`,
+				`expression: relation "t" (51): computed column "col" references unknown column "missing" in expression: missing + 1`,
+			},
+		},
+	})
+}
+
+func TestCheckDefaultExpressions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "default expressions",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					defaultExpr := "nextval(60:::REGCLASS) + nextval(52:::REGCLASS) + nextval(53:::REGCLASS)"
+					tbl.Columns[0].DefaultExpr = &defaultExpr
+					tbl.Columns[0].UsesSequenceIds = []descpb.ID{53}
+				}))},
+				dbRow(t),
+				{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "s"
+					tbl.ID = 53
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("s")
+					tbl.SequenceOpts = &descpb.TableDescriptor_SequenceOpts{
+						Increment: 1, MinValue: 1, MaxValue: 100, Start: 1,
+					}
+				}))},
+				{ID: 54, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "u"
+					tbl.ID = 54
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("u")
+					defaultExpr := "nextval("
+					tbl.Columns[0].DefaultExpr = &defaultExpr
+				}))},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("s", 53), tableNamespaceRow("u", 54),
+			},
+			expected: []string{
+				`sequence reference: relation "t" (51): default of column "col" uses missing sequence 60`,
+				`sequence reference: relation "t" (51): default of column "col" uses sequence 60, which the column doesn't list as used`,
+				`sequence reference: relation "t" (51): default of column "col" uses database "db" (52), which is not a sequence`,
+				`sequence reference: relation "t" (51): default of column "col" uses sequence 52, which the column doesn't list as used`,
+				`expression: relation "u" (54): default of column "col" has an expression which doesn't parse: nextval(: at or near "EOF": syntax error`,
+			},
+		},
+	})
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+)
+
+// ExamineGraph writes a Graphviz DOT graph of the references between the
+// descriptors of the descriptor table. Each descriptor is a node labeled with
+// its kind, name and ID. Edges go from each descriptor to its parent database
+// and schema, from each table to the tables referenced by its foreign keys,
+// from each view to the relations it depends on and from each sequence to the
+// table owning it.
+//
+// Edges to missing descriptors are red and point to a red node standing in
+// for the missing descriptor. Foreign keys and view dependencies lacking the
+// corresponding back-reference, which the doctor reports as one-sided, are
+// dashed.
+func ExamineGraph(
+	ctx context.Context, descTable DescriptorTable, namespaceTable NamespaceTable, w io.Writer,
+) error {
+	ddg, err := newDescGetter(ctx, newExamination(nil /* opts */), descTable, namespaceTable)
+	if err != nil {
+		return err
+	}
+	g := descGraph{ddg: ddg, missing: make(map[descpb.ID]struct{})}
+	fmt.Fprintln(w, "digraph descriptors {")
+	// Rows with duplicate IDs only make for one node.
+	var descs []catalog.Descriptor
+	seen := make(map[descpb.ID]struct{}, len(descTable))
+	for _, row := range descTable {
+		id := descpb.ID(row.ID)
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		if desc, ok := ddg.Descriptors[id]; ok {
+			descs = append(descs, desc)
+			fmt.Fprintf(w, "  d%d [label=%s];\n", id, dotQuote(descSubject(desc).String()))
+		}
+	}
+	for _, desc := range descs {
+		g.writeEdges(w, desc)
+	}
+	missing := make([]descpb.ID, 0, len(g.missing))
+	for id := range g.missing {
+		missing = append(missing, id)
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	for _, id := range missing {
+		fmt.Fprintf(w, "  d%d [label=%s, color=red];\n", id, dotQuote(fmt.Sprintf("missing (%d)", id)))
+	}
+	fmt.Fprintln(w, "}")
+	return nil
+}
+
+// descGraph writes the edges of the graph output by ExamineGraph.
+type descGraph struct {
+	ddg catalog.MapDescGetter
+	// missing collects the IDs of the missing descriptors referenced by edges.
+	missing map[descpb.ID]struct{}
+}
+
+// writeEdges writes the edges from desc to the descriptors it references.
+func (g *descGraph) writeEdges(w io.Writer, desc catalog.Descriptor) {
+	if id := desc.GetParentID(); id != descpb.InvalidID {
+		g.writeEdge(w, desc.GetID(), id, "parent", false /* oneSided */)
+	}
+	if id := desc.GetParentSchemaID(); id != descpb.InvalidID && id != keys.PublicSchemaID {
+		g.writeEdge(w, desc.GetID(), id, "schema", false /* oneSided */)
+	}
+	table, ok := desc.(catalog.TableDescriptor)
+	if !ok {
+		return
+	}
+	tbl := table.TableDesc()
+	for i := range tbl.OutboundFKs {
+		fk := &tbl.OutboundFKs[i]
+		oneSided := false
+		if referenced := lookupTable(g.ddg, fk.ReferencedTableID); referenced != nil {
+			oneSided = !hasForeignKey(referenced.TableDesc().InboundFKs, fk.Name, tbl.ID, fk.ReferencedTableID)
+		}
+		g.writeEdge(w, tbl.ID, fk.ReferencedTableID, fmt.Sprintf("foreign key %q", fk.Name), oneSided)
+	}
+	for _, id := range tbl.DependsOn {
+		oneSided := false
+		if dependedOn := lookupTable(g.ddg, id); dependedOn != nil {
+			oneSided = !hasDependent(dependedOn.TableDesc().DependedOnBy, tbl.ID)
+		}
+		g.writeEdge(w, tbl.ID, id, "depends on", oneSided)
+	}
+	if table.IsSequence() {
+		if owner := table.GetSequenceOpts().SequenceOwner; owner.OwnerTableID != descpb.InvalidID {
+			g.writeEdge(w, tbl.ID, owner.OwnerTableID, "owned by", false /* oneSided */)
+		}
+	}
+}
+
+// writeEdge writes an edge with the given label, which is red if the
+// descriptor with ID to is missing and dashed if the reference is one-sided.
+func (g *descGraph) writeEdge(w io.Writer, from, to descpb.ID, label string, oneSided bool) {
+	attrs := "label=" + dotQuote(label)
+	if _, ok := g.ddg.Descriptors[to]; !ok {
+		g.missing[to] = struct{}{}
+		attrs += ", color=red"
+	} else if oneSided {
+		attrs += ", style=dashed"
+	}
+	fmt.Fprintf(w, "  d%d -> d%d [%s];\n", from, to, attrs)
+}
+
+// dotQuoter escapes the characters which are special in DOT strings.
+var dotQuoter = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// dotQuote returns s as a quoted DOT string.
+func dotQuote(s string) string {
+	return `"` + dotQuoter.Replace(s) + `"`
+}
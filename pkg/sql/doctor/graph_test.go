@@ -0,0 +1,62 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExamineGraph(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.OutboundFKs = []descpb.ForeignKeyConstraint{{
+				Name:                "fk",
+				OriginTableID:       51,
+				OriginColumnIDs:     []descpb.ColumnID{1},
+				ReferencedTableID:   53,
+				ReferencedColumnIDs: []descpb.ColumnID{1},
+			}}
+		}))},
+		{ID: 52, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+			Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+		}})},
+		{ID: 54, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.Name = "v"
+			tbl.ID = 54
+			tbl.ViewQuery = "SELECT col FROM db.public.t"
+			tbl.DependsOn = []descpb.ID{51}
+		}))},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, doctor.ExamineGraph(context.Background(), descTable, nil /* namespaceTable */, &buf))
+	require.Equal(t, `digraph descriptors {
+  d51 [label="relation \"t\" (51)"];
+  d52 [label="database \"db\" (52)"];
+  d54 [label="relation \"v\" (54)"];
+  d51 -> d52 [label="parent"];
+  d51 -> d53 [label="foreign key \"fk\"", color=red];
+  d54 -> d52 [label="parent"];
+  d54 -> d51 [label="depends on", style=dashed];
+  d53 [label="missing (53)", color=red];
+}
+`, buf.String())
+}
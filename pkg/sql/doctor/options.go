@@ -0,0 +1,183 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// ExamineOption configures an examination of the descriptor table.
+type ExamineOption func(e *examination)
+
+// DescriptorKind selects descriptors by kind.
+type DescriptorKind int
+
+const (
+	// DescriptorKindAll selects all descriptors.
+	DescriptorKindAll DescriptorKind = iota
+	// DescriptorKindTable selects table and view descriptors, but not
+	// sequences.
+	DescriptorKindTable
+	// DescriptorKindDatabase selects database descriptors.
+	DescriptorKindDatabase
+	// DescriptorKindSchema selects schema descriptors.
+	DescriptorKindSchema
+	// DescriptorKindType selects type descriptors.
+	DescriptorKindType
+	// DescriptorKindSequence selects sequence descriptors.
+	DescriptorKindSequence
+)
+
+// String implements the fmt.Stringer interface.
+func (k DescriptorKind) String() string {
+	switch k {
+	case DescriptorKindAll:
+		return "all"
+	case DescriptorKindTable:
+		return "table"
+	case DescriptorKindDatabase:
+		return "database"
+	case DescriptorKindSchema:
+		return "schema"
+	case DescriptorKindType:
+		return "type"
+	case DescriptorKindSequence:
+		return "sequence"
+	default:
+		return fmt.Sprintf("DescriptorKind(%d)", int(k))
+	}
+}
+
+// matches returns whether desc, which may be nil, is of this kind. Missing
+// descriptors only match DescriptorKindAll.
+func (k DescriptorKind) matches(desc catalog.Descriptor) bool {
+	return k == DescriptorKindAll || k == descriptorKind(desc)
+}
+
+// descriptorKind returns the kind of desc, or DescriptorKindAll if desc is nil
+// or of no known kind.
+func descriptorKind(desc catalog.Descriptor) DescriptorKind {
+	switch desc := desc.(type) {
+	case catalog.TableDescriptor:
+		if desc.IsSequence() {
+			return DescriptorKindSequence
+		}
+		return DescriptorKindTable
+	case catalog.DatabaseDescriptor:
+		return DescriptorKindDatabase
+	case catalog.SchemaDescriptor:
+		return DescriptorKindSchema
+	case catalog.TypeDescriptor:
+		return DescriptorKindType
+	default:
+		return DescriptorKindAll
+	}
+}
+
+// WithDescriptorKind restricts the examination to descriptors of the given
+// kind, and to the namespace entries pointing to them. Descriptors of other
+// kinds are still used to check references, but their problems aren't
+// reported.
+func WithDescriptorKind(kind DescriptorKind) ExamineOption {
+	return func(e *examination) {
+		e.kind = kind
+	}
+}
+
+// WithModifiedSince restricts the examination to descriptors modified at or
+// after since, and to the namespace entries pointing to them, for a quick look
+// at the aftermath of a schema change. As with WithDescriptorKind, the other
+// descriptors are still used to check references, but their problems aren't
+// reported. Namespace entries pointing to missing descriptors aren't examined.
+func WithModifiedSince(since hlc.Timestamp) ExamineOption {
+	return func(e *examination) {
+		e.modifiedSince = since
+	}
+}
+
+// WithMinSeverity discards the problems less severe than the given severity.
+func WithMinSeverity(severity Severity) ExamineOption {
+	return func(e *examination) {
+		e.minSeverity = severity
+	}
+}
+
+// WithIgnore suppresses the problems found in the descriptors and namespace
+// entries with the given IDs: they aren't reported and don't affect the result
+// of the examination, only their number is.
+func WithIgnore(ids ...descpb.ID) ExamineOption {
+	return func(e *examination) {
+		if e.ignoreIDs == nil {
+			e.ignoreIDs = make(map[descpb.ID]struct{}, len(ids))
+		}
+		for _, id := range ids {
+			e.ignoreIDs[id] = struct{}{}
+		}
+	}
+}
+
+// WithSystemSchemaCheck enables comparing the descriptors of the system tables
+// with the definitions these tables are bootstrapped with in this version.
+// Clusters upgraded from older versions may legitimately keep older
+// definitions until some migration runs, hence this is opt-in: it's meant for
+// examining a cluster at the same version as the doctor.
+func WithSystemSchemaCheck() ExamineOption {
+	return func(e *examination) {
+		e.checkSystemSchema = true
+	}
+}
+
+// WithVersionCheck enables checking that live descriptors have a non-zero
+// version and a modification time which isn't after now, as either breaks
+// leasing. It's opt-in because the rows of a descriptor table don't always
+// come with the MVCC timestamps which modification times derive from: those
+// read from a debug zip get the current time instead.
+func WithVersionCheck(now hlc.Timestamp) ExamineOption {
+	return func(e *examination) {
+		e.versionCheckNow = now
+	}
+}
+
+// WithDeduplication makes the non-verbose text output collapse problems of the
+// same kind with messages of the same form, such as the same validation error,
+// into a single line, rather than giving one line per descriptor or namespace
+// entry with problems. The line gives the number of these problems and lists
+// the IDs of the descriptors and namespace entries affected, at most maxIDs of
+// them. Verbose output is left in full detail. A maxIDs of 0 disables
+// deduplication.
+func WithDeduplication(maxIDs int) ExamineOption {
+	return func(e *examination) {
+		e.dedupMaxIDs = maxIDs
+	}
+}
+
+// WithSizeStats makes the summary of the text output include the number of
+// descriptors of each kind examined, with the minimum, maximum and total size
+// of their serialized representation. Oversized descriptors, such as tables
+// with thousands of partitions, are worth looking into even when valid.
+func WithSizeStats() ExamineOption {
+	return func(e *examination) {
+		e.sizes = make(descriptorSizes)
+	}
+}
+
+// newExamination returns an examination configured with opts.
+func newExamination(opts []ExamineOption) *examination {
+	e := &examination{}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
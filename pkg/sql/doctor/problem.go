@@ -0,0 +1,747 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+)
+
+// ProblemKind categorizes the problems found by the doctor.
+type ProblemKind int
+
+const (
+	_ ProblemKind = iota
+	// UpgradeFailure is for descriptors which could not be upgraded after
+	// having been deserialized.
+	UpgradeFailure
+	// DescriptorIDMismatch is for descriptors whose ID differs from the ID of
+	// their row in the descriptor table.
+	DescriptorIDMismatch
+	// DuplicateDescriptorID is for descriptor table rows which share their ID
+	// with another row.
+	DuplicateDescriptorID
+	// ValidationFailure is for errors returned by descriptor validation.
+	ValidationFailure
+	// InvalidJobReference is for descriptors referencing a mutation job which
+	// is missing or inconsistent.
+	InvalidJobReference
+	// InvalidNamespaceEntry is for namespace entries which don't match any
+	// descriptor, or which match a descriptor of the wrong kind or name.
+	InvalidNamespaceEntry
+	// DanglingForeignKey is for foreign key references to or from a table
+	// which doesn't exist.
+	DanglingForeignKey
+	// OneSidedForeignKey is for foreign key references which lack the
+	// corresponding reference on the other table.
+	OneSidedForeignKey
+	// InvalidIndexColumn is for indexes storing columns which don't exist, or
+	// whose stored column names don't match their stored column IDs.
+	InvalidIndexColumn
+	// InvalidColumnFamily is for column families whose default column isn't
+	// in the family, or is a primary key column.
+	InvalidColumnFamily
+	// StaleIDCounter is for tables whose Next* counters aren't greater than
+	// the IDs already allocated with them.
+	StaleIDCounter
+	// DanglingTypeReference is for references to or from a type which point
+	// to a missing descriptor.
+	DanglingTypeReference
+	// InvalidTypeReference is for references to or from a type which point to
+	// a descriptor of the wrong kind.
+	InvalidTypeReference
+	// DanglingDependency is for view dependencies to or from a relation which
+	// doesn't exist.
+	DanglingDependency
+	// OneSidedDependency is for view dependencies which lack the corresponding
+	// reference on the other relation.
+	OneSidedDependency
+	// InvalidSequenceOwner is for sequences owned by a column which doesn't
+	// exist or doesn't list the sequence as owned.
+	InvalidSequenceOwner
+	// DuplicateNamespaceEntry is for descriptors referenced by more than one
+	// namespace entry other than their draining names.
+	DuplicateNamespaceEntry
+	// InvalidPrivilege is for descriptors whose stored privileges are
+	// inconsistent, such as users with privileges invalid for the descriptor.
+	InvalidPrivilege
+	// InvalidExpression is for expressions stored in a table which don't parse
+	// or reference columns which don't exist.
+	InvalidExpression
+	// InvalidSequenceReference is for column defaults using a sequence which
+	// doesn't exist or which the column doesn't list as used.
+	InvalidSequenceReference
+	// LingeringNamespaceEntry is for dropped descriptors which are still
+	// referenced by namespace entries other than those of their draining
+	// names.
+	LingeringNamespaceEntry
+	// DanglingInterleave is for interleave references to or from an index
+	// which doesn't exist.
+	DanglingInterleave
+	// OneSidedInterleave is for interleave references which lack the
+	// corresponding reference on the other index.
+	OneSidedInterleave
+	// SystemSchemaMismatch is for system tables whose columns, indexes or
+	// privileges differ from those the table is bootstrapped with.
+	SystemSchemaMismatch
+	// DanglingSchemaParent is for schemas whose parent database doesn't exist.
+	DanglingSchemaParent
+	// OneSidedSchemaParent is for schemas which their parent database doesn't
+	// list among its schemas.
+	OneSidedSchemaParent
+	// DanglingSchemaEntry is for entries of a database's schemas mapping
+	// which refer to a missing or dropped schema.
+	DanglingSchemaEntry
+	// OneSidedSchemaEntry is for entries of a database's schemas mapping
+	// which refer to a schema of another database.
+	OneSidedSchemaEntry
+	// IncompletePrimaryIndex is for tables whose primary index doesn't store
+	// all of their non-virtual columns.
+	IncompletePrimaryIndex
+	// InvalidColumnType is for columns whose type is missing or malformed.
+	InvalidColumnType
+	// InvalidPartitioning is for index partitionings which use more columns
+	// than the index has, or whose partition names aren't unique.
+	InvalidPartitioning
+	// InvalidVersion is for live descriptors whose version is zero, or whose
+	// modification time is missing or in the future.
+	InvalidVersion
+	// InvalidMutation is for table mutations which are out of order, or which
+	// add or drop a column or index inconsistently with the rest of the table.
+	InvalidMutation
+	// InvalidParentSchema is for tables and types whose parent schema is
+	// missing, dropped or in another database.
+	InvalidParentSchema
+	// DroppedReference is for references from live descriptors to dropped
+	// ones, which should have been removed along with the dropped descriptor.
+	DroppedReference
+	// NullableKeyColumn is for nullable columns in the key of a primary index,
+	// which assumes that its key columns are never NULL, or of a unique index
+	// without the primary key columns to tell NULLs apart with.
+	NullableKeyColumn
+	// ReservedID is for user descriptors with an ID reserved for system
+	// descriptors, and for system descriptors without one.
+	ReservedID
+	// DuplicateColumn is for columns sharing a name or an ID with another
+	// column of the same table.
+	DuplicateColumn
+	// ParentCycle is for descriptors which are their own ancestors, or which
+	// have implausibly many ancestors.
+	ParentCycle
+	// InvalidEnumMember is for enum members with an empty or duplicate name or
+	// physical representation, or which are out of order.
+	InvalidEnumMember
+	// StaleDrainingName is for draining names which keep their namespace entry
+	// without any schema change job left to remove it, as after a stuck rename.
+	StaleDrainingName
+	// InvalidHiddenColumn is for visible columns which look like the implicit
+	// row ID column of their table.
+	InvalidHiddenColumn
+	// InvalidName is for live descriptors whose name is empty, made of
+	// whitespace only, or otherwise unusable as an SQL identifier.
+	InvalidName
+	// InvalidSequenceOptions is for sequences whose options keep nextval from
+	// advancing them, such as a zero increment or an empty range.
+	InvalidSequenceOptions
+	// InvalidIndexVersion is for indexes whose version is newer than the
+	// latest one supported, whose encoding may not be readable.
+	InvalidIndexVersion
+	// InvalidFormatVersion is for tables whose format version is newer than
+	// the latest one supported, or older than the features they use.
+	InvalidFormatVersion
+	// ObsoleteIndexVersion is for indexes whose version is older than the
+	// latest one and can't be upgraded when read.
+	ObsoleteIndexVersion
+)
+
+// Severity returns the severity of problems of this kind. All problems are
+// errors, except for stale ID counters, which only matter once the next ID is
+// allocated, invalid sequence owners, which only matter once the owner is
+// dropped, visible columns which look like an implicit row ID column, which
+// may just as well have been declared so, and obsolete index versions, which
+// are still readable. These are warnings.
+func (k ProblemKind) Severity() Severity {
+	switch k {
+	case StaleIDCounter, InvalidSequenceOwner, InvalidHiddenColumn, ObsoleteIndexVersion:
+		return SeverityWarning
+	default:
+		return SeverityError
+	}
+}
+
+// String implements the fmt.Stringer interface.
+func (k ProblemKind) String() string {
+	switch k {
+	case UpgradeFailure:
+		return "upgrade failure"
+	case DescriptorIDMismatch:
+		return "descriptor ID mismatch"
+	case DuplicateDescriptorID:
+		return "duplicate descriptor ID"
+	case ValidationFailure:
+		return "validation failure"
+	case InvalidJobReference:
+		return "invalid job reference"
+	case InvalidNamespaceEntry:
+		return "invalid namespace entry"
+	case DanglingForeignKey:
+		return "dangling foreign key"
+	case OneSidedForeignKey:
+		return "one-sided foreign key"
+	case InvalidIndexColumn:
+		return "index column"
+	case InvalidColumnFamily:
+		return "column family"
+	case StaleIDCounter:
+		return "stale ID counter"
+	case DanglingTypeReference:
+		return "dangling type reference"
+	case InvalidTypeReference:
+		return "invalid type reference"
+	case DanglingDependency:
+		return "dangling dependency"
+	case OneSidedDependency:
+		return "one-sided dependency"
+	case InvalidSequenceOwner:
+		return "sequence ownership"
+	case DuplicateNamespaceEntry:
+		return "duplicate namespace entry"
+	case InvalidPrivilege:
+		return "privilege"
+	case InvalidExpression:
+		return "expression"
+	case InvalidSequenceReference:
+		return "sequence reference"
+	case LingeringNamespaceEntry:
+		return "lingering namespace entry"
+	case DanglingInterleave:
+		return "dangling interleave"
+	case OneSidedInterleave:
+		return "one-sided interleave"
+	case SystemSchemaMismatch:
+		return "system schema mismatch"
+	case DanglingSchemaParent:
+		return "dangling schema parent"
+	case OneSidedSchemaParent:
+		return "one-sided schema parent"
+	case DanglingSchemaEntry:
+		return "dangling schema entry"
+	case OneSidedSchemaEntry:
+		return "one-sided schema entry"
+	case IncompletePrimaryIndex:
+		return "incomplete primary index"
+	case InvalidColumnType:
+		return "column type"
+	case InvalidPartitioning:
+		return "partitioning"
+	case InvalidVersion:
+		return "version"
+	case InvalidMutation:
+		return "mutation"
+	case InvalidParentSchema:
+		return "parent schema"
+	case DroppedReference:
+		return "dropped reference"
+	case NullableKeyColumn:
+		return "nullable key column"
+	case ReservedID:
+		return "reserved ID"
+	case DuplicateColumn:
+		return "duplicate column"
+	case ParentCycle:
+		return "parent cycle"
+	case InvalidEnumMember:
+		return "enum member"
+	case StaleDrainingName:
+		return "stale draining name"
+	case InvalidHiddenColumn:
+		return "hidden column"
+	case InvalidName:
+		return "invalid name"
+	case InvalidSequenceOptions:
+		return "sequence options"
+	case InvalidIndexVersion:
+		return "index version"
+	case InvalidFormatVersion:
+		return "format version"
+	case ObsoleteIndexVersion:
+		return "obsolete index version"
+	default:
+		return fmt.Sprintf("ProblemKind(%d)", int(k))
+	}
+}
+
+// Severity distinguishes the problems which need fixing from those which
+// merely warrant attention.
+type Severity int
+
+const (
+	// SeverityWarning is for problems which don't yet prevent the descriptors
+	// from working correctly, but may eventually.
+	SeverityWarning Severity = iota
+	// SeverityError is for problems which make descriptors unusable or
+	// inconsistent.
+	SeverityError
+)
+
+// String implements the fmt.Stringer interface.
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// NamespaceEntryType is the Subject type of namespace entries.
+const NamespaceEntryType = "namespace entry"
+
+// Subject identifies the descriptor or namespace entry that a Problem
+// pertains to.
+type Subject struct {
+	// DescriptorID is the ID of the descriptor, or the ID in the namespace
+	// entry.
+	DescriptorID descpb.ID
+	// DescriptorType is the catalog.DescriptorType of the descriptor, or
+	// NamespaceEntryType.
+	DescriptorType string
+	ParentID       descpb.ID
+	ParentSchemaID descpb.ID
+	Name           string
+}
+
+// String implements the fmt.Stringer interface. The format is the same as the
+// prefix which descriptor validation errors are wrapped with.
+func (s Subject) String() string {
+	return fmt.Sprintf("%s %q (%d)", s.DescriptorType, s.Name, s.DescriptorID)
+}
+
+func descSubject(desc catalog.Descriptor) Subject {
+	return Subject{
+		DescriptorID:   desc.GetID(),
+		DescriptorType: string(desc.DescriptorType()),
+		ParentID:       desc.GetParentID(),
+		ParentSchemaID: desc.GetParentSchemaID(),
+		Name:           desc.GetName(),
+	}
+}
+
+func nsSubject(row NamespaceTableRow) Subject {
+	return Subject{
+		DescriptorID:   descpb.ID(row.ID),
+		DescriptorType: NamespaceEntryType,
+		ParentID:       row.ParentID,
+		ParentSchemaID: row.ParentSchemaID,
+		Name:           row.Name,
+	}
+}
+
+// Problem is an inconsistency found by the doctor.
+type Problem struct {
+	Subject
+	Kind     ProblemKind
+	Severity Severity
+	// Message describes the problem, without the Subject.
+	Message string
+	// template is the format of Message, or Message itself if it was only
+	// passed through. Problems of the same kind and template are alike.
+	template string
+}
+
+// examination accumulates the results of examining the system tables, in the
+// order in which they were found.
+type examination struct {
+	problems []Problem
+	// processed lists the descriptors and namespace entries which were
+	// examined, for verbose output.
+	processed []processedEntry
+	// only, if set, restricts the examination to the descriptor table rows and
+	// namespace entries with this ID.
+	only descpb.ID
+	// kind restricts the examination to descriptors of this kind, and to the
+	// namespace entries pointing to them.
+	kind DescriptorKind
+	// modifiedSince, if set, restricts the examination to descriptors modified
+	// at or after this time, and to the namespace entries pointing to them.
+	modifiedSince hlc.Timestamp
+	// minSeverity is the severity below which problems are discarded.
+	minSeverity Severity
+	// ignoreIDs are the IDs of the descriptors and namespace entries whose
+	// problems are suppressed, numSuppressed counts those problems.
+	ignoreIDs     map[descpb.ID]struct{}
+	numSuppressed int
+	// checkSystemSchema enables comparing the system tables with their
+	// bootstrap definitions.
+	checkSystemSchema bool
+	// versionCheckNow, if set, enables checking descriptor versions and
+	// modification times against this time.
+	versionCheckNow hlc.Timestamp
+	// dedupMaxIDs, if set, enables writing problems alike as a single line in
+	// non-verbose text output, listing at most this many of their IDs.
+	dedupMaxIDs int
+	// sizes, if set, accumulates the sizes of the descriptors examined, which
+	// are then written after the summary.
+	sizes descriptorSizes
+	// numElided counts the problems left out of the brief text output, which
+	// only gives the first problem of each descriptor or namespace entry.
+	numElided int
+	// partial is set when the descriptors are only part of a catalog, as in a
+	// backup, without its namespace table: namespace entries aren't checked,
+	// and references to missing descriptors are only warnings, as those may
+	// just be outside of the examined descriptors.
+	partial bool
+	// numSkipped counts the descriptors in scope which weren't examined
+	// because their ID is duplicated or doesn't match their row's.
+	numSkipped int
+}
+
+type processedEntry struct {
+	Subject
+	// numProblems is the number of problems found up to and including the
+	// examination of this entry.
+	numProblems int
+	// invalid is set for namespace entries which failed validation. Unlike
+	// descriptors, these aren't reported as processed in verbose mode.
+	invalid bool
+}
+
+// inScope returns whether the descriptor table row or namespace entry with the
+// given ID is to be examined. desc is the descriptor with that ID, if any.
+func (e *examination) inScope(id descpb.ID, desc catalog.Descriptor) bool {
+	return (e.only == descpb.InvalidID || e.only == id) && e.kind.matches(desc) &&
+		(e.modifiedSince.IsEmpty() || (desc != nil && !desc.GetModificationTime().Less(e.modifiedSince)))
+}
+
+// restriction describes the restriction of the examination to recently
+// modified descriptors, if any, for the line starting the text output.
+func (e *examination) restriction() string {
+	if e.modifiedSince.IsEmpty() {
+		return ""
+	}
+	return fmt.Sprintf(", restricted to the descriptors modified since %s", e.modifiedSince)
+}
+
+// add records p, unless it's less severe than the minimum severity or is
+// suppressed by the ignore-list.
+func (e *examination) add(p Problem) {
+	if p.Severity < e.minSeverity {
+		return
+	}
+	if _, ok := e.ignoreIDs[p.DescriptorID]; ok {
+		e.numSuppressed++
+		return
+	}
+	e.problems = append(e.problems, p)
+}
+
+func (e *examination) descReport(
+	desc catalog.Descriptor, kind ProblemKind, format string, args ...interface{},
+) {
+	e.descReportWithSeverity(desc, kind, kind.Severity(), format, args...)
+}
+
+// missingReport reports desc for referencing a missing descriptor, which is
+// only a warning in partial examinations.
+func (e *examination) missingReport(
+	desc catalog.Descriptor, kind ProblemKind, format string, args ...interface{},
+) {
+	severity := kind.Severity()
+	if e.partial {
+		severity = SeverityWarning
+	}
+	e.descReportWithSeverity(desc, kind, severity, format, args...)
+}
+
+func (e *examination) descReportWithSeverity(
+	desc catalog.Descriptor,
+	kind ProblemKind,
+	severity Severity,
+	format string,
+	args ...interface{},
+) {
+	s := descSubject(desc)
+	// Strip the descriptor-identifying prefix if it's there already, as is the
+	// case with validation errors.
+	msg := strings.TrimPrefix(fmt.Sprintf(format, args...), s.String()+": ")
+	e.add(Problem{
+		Subject:  s,
+		Kind:     kind,
+		Severity: severity,
+		Message:  msg,
+		template: problemTemplate(format, msg),
+	})
+}
+
+func (e *examination) nsReport(
+	row NamespaceTableRow, kind ProblemKind, format string, args ...interface{},
+) {
+	msg := fmt.Sprintf(format, args...)
+	e.add(Problem{
+		Subject:  nsSubject(row),
+		Kind:     kind,
+		Severity: kind.Severity(),
+		Message:  msg,
+		template: problemTemplate(format, msg),
+	})
+}
+
+// problemTemplate returns the template of a problem message formatted with
+// format. Messages which are passed through, such as validation errors, are
+// their own template.
+func problemTemplate(format, msg string) string {
+	if format == "%s" || format == "%v" {
+		return msg
+	}
+	return format
+}
+
+// hasErrors returns whether any of the problems found is an error.
+func (e *examination) hasErrors() bool {
+	for i := range e.problems {
+		if e.problems[i].Severity >= SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *examination) descProcessed(desc catalog.Descriptor) {
+	e.processed = append(e.processed, processedEntry{
+		Subject:     descSubject(desc),
+		numProblems: len(e.problems),
+	})
+}
+
+func (e *examination) nsProcessed(row NamespaceTableRow, invalid bool) {
+	e.processed = append(e.processed, processedEntry{
+		Subject:     nsSubject(row),
+		numProblems: len(e.problems),
+		invalid:     invalid,
+	})
+}
+
+// visit calls fn for each problem in the order in which they were found.
+// In verbose mode, fn is also called for each processed entry after its
+// problems, with a nil Problem.
+func (e *examination) visit(verbose bool, fn func(s Subject, p *Problem)) {
+	i := 0
+	for _, pe := range e.processed {
+		for ; i < pe.numProblems; i++ {
+			fn(e.problems[i].Subject, &e.problems[i])
+		}
+		if verbose && !pe.invalid {
+			fn(pe.Subject, nil /* p */)
+		}
+	}
+	for ; i < len(e.problems); i++ {
+		fn(e.problems[i].Subject, &e.problems[i])
+	}
+}
+
+// writeText writes the human-readable representation of the problems.
+func (e *examination) writeText(w io.Writer, verbose bool) {
+	if e.dedupMaxIDs > 0 && !verbose {
+		e.writeDeduplicatedText(w)
+		return
+	}
+	e.visit(verbose, func(s Subject, p *Problem) {
+		msg := "processed"
+		if p != nil {
+			msg = p.text()
+		}
+		writeTextLine(w, s, msg)
+	})
+}
+
+// writeBriefText writes the human-readable representation of the problems in
+// non-verbose mode. It's like writeText, except that the problems of each
+// descriptor or namespace entry are written as a single line, where the first
+// of them was found, which gives the number of the others. Their detail is
+// elided, and they're counted as such in the summary. Deduplication, if
+// enabled, takes precedence.
+func (e *examination) writeBriefText(w io.Writer) {
+	if e.dedupMaxIDs > 0 {
+		e.writeDeduplicatedText(w)
+		return
+	}
+	var firsts []*Problem
+	counts := make(map[Subject]int)
+	for i := range e.problems {
+		p := &e.problems[i]
+		if counts[p.Subject] == 0 {
+			firsts = append(firsts, p)
+		}
+		counts[p.Subject]++
+	}
+	for _, p := range firsts {
+		msg := p.text()
+		if n := counts[p.Subject] - 1; n > 0 {
+			msg = fmt.Sprintf("%s (and %d more %s)", msg, n, pluralize(n, "problem"))
+			e.numElided += n
+		}
+		writeTextLine(w, p.Subject, msg)
+	}
+}
+
+// writeDeduplicatedText writes the human-readable representation of the
+// problems like writeText, except that problems alike are written as a single
+// line, where the first of them was found. The line gives their number and the
+// IDs of their subjects, up to dedupMaxIDs of them.
+func (e *examination) writeDeduplicatedText(w io.Writer) {
+	type problemKey struct {
+		kind     ProblemKind
+		template string
+	}
+	type problemGroup struct {
+		first *Problem
+		ids   []string
+	}
+	var groups []*problemGroup
+	byKey := make(map[problemKey]*problemGroup)
+	for i := range e.problems {
+		p := &e.problems[i]
+		key := problemKey{kind: p.Kind, template: p.template}
+		g, ok := byKey[key]
+		if !ok {
+			g = &problemGroup{first: p}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.ids = append(g.ids, fmt.Sprint(p.DescriptorID))
+	}
+	for _, g := range groups {
+		msg := g.first.text()
+		if n := len(g.ids); n > 1 {
+			ids := g.ids
+			if n > e.dedupMaxIDs {
+				ids = append(ids[:e.dedupMaxIDs:e.dedupMaxIDs], fmt.Sprintf("%d more", n-e.dedupMaxIDs))
+			}
+			msg = fmt.Sprintf("%s (%d problems like this one, affecting IDs %s)",
+				msg, n, strings.Join(ids, ", "))
+		}
+		writeTextLine(w, g.first.Subject, msg)
+	}
+}
+
+// text returns the message of the problem as written by writeText.
+func (p *Problem) text() string {
+	if p.Severity == SeverityWarning {
+		return "warning: " + p.Message
+	}
+	return p.Message
+}
+
+// writeTextLine writes a line of the human-readable representation of the
+// problems.
+func writeTextLine(w io.Writer, s Subject, msg string) {
+	_, _ = fmt.Fprintf(w, "  ParentID %3d, ParentSchemaID %2d: %s: %s\n",
+		s.ParentID, s.ParentSchemaID, s, msg)
+}
+
+// writeSummary writes the number of problems of each kind and of examined
+// and skipped entries, unless no problems were found, followed by the number of problems
+// suppressed by the ignore-list and of those elided from brief text output, if
+// any, and the descriptor sizes, if requested.
+func (e *examination) writeSummary(w io.Writer) {
+	if len(e.problems) > 0 {
+		var kinds []ProblemKind
+		counts := make(map[ProblemKind]int)
+		for _, p := range e.problems {
+			if counts[p.Kind] == 0 {
+				kinds = append(kinds, p.Kind)
+			}
+			counts[p.Kind]++
+		}
+		sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+		byKind := make([]string, len(kinds))
+		for i, k := range kinds {
+			byKind[i] = fmt.Sprintf("%d %s", counts[k], k)
+		}
+		_, _ = fmt.Fprintf(w, "Found %d %s: %s\n",
+			len(e.problems), pluralize(len(e.problems), "problem"), strings.Join(byKind, ", "))
+		var numDescs, numNamespaceEntries int
+		for _, pe := range e.processed {
+			if pe.DescriptorType == NamespaceEntryType {
+				numNamespaceEntries++
+			} else {
+				numDescs++
+			}
+		}
+		var skipped string
+		if e.numSkipped > 0 {
+			skipped = fmt.Sprintf(" (%d skipped: duplicate or mismatched IDs)", e.numSkipped)
+		}
+		_, _ = fmt.Fprintf(w, "Examined %d descriptors and %d namespace entries%s.\n",
+			numDescs, numNamespaceEntries, skipped)
+	}
+	if e.numSuppressed > 0 {
+		_, _ = fmt.Fprintf(w, "%d %s suppressed by ignore-list.\n",
+			e.numSuppressed, pluralize(e.numSuppressed, "problem"))
+	}
+	if e.numElided > 0 {
+		_, _ = fmt.Fprintf(w, "%d %s elided, run with --verbose for per-row detail.\n",
+			e.numElided, pluralize(e.numElided, "problem"))
+	}
+	if e.sizes != nil {
+		e.sizes.write(w)
+	}
+}
+
+// pluralize returns noun, made plural unless n is 1.
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return noun
+	}
+	return noun + "s"
+}
+
+// jsonProblem is the JSON representation of a Problem.
+type jsonProblem struct {
+	DescriptorID   descpb.ID `json:"descriptorID"`
+	DescriptorType string    `json:"descriptorType"`
+	ParentID       descpb.ID `json:"parentID"`
+	ParentSchemaID descpb.ID `json:"parentSchemaID"`
+	Name           string    `json:"name"`
+	Kind           string    `json:"kind,omitempty"`
+	Severity       string    `json:"severity,omitempty"`
+	Message        string    `json:"message"`
+}
+
+// writeJSON writes one JSON object per line for each problem.
+func (e *examination) writeJSON(w io.Writer, verbose bool) {
+	enc := json.NewEncoder(w)
+	e.visit(verbose, func(s Subject, p *Problem) {
+		jp := jsonProblem{
+			DescriptorID:   s.DescriptorID,
+			DescriptorType: s.DescriptorType,
+			ParentID:       s.ParentID,
+			ParentSchemaID: s.ParentSchemaID,
+			Name:           s.Name,
+			Message:        "processed",
+		}
+		if p != nil {
+			jp.Kind = p.Kind.String()
+			jp.Severity = p.Severity.String()
+			jp.Message = p.Message
+		}
+		_ = enc.Encode(jp)
+	})
+}
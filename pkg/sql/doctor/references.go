@@ -0,0 +1,487 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+)
+
+// lookupTable returns the table descriptor with the given ID, or nil if there
+// is no such descriptor or if it is not a table.
+func lookupTable(ddg catalog.MapDescGetter, id descpb.ID) catalog.TableDescriptor {
+	tbl, _ := ddg.Descriptors[id].(catalog.TableDescriptor)
+	return tbl
+}
+
+// checkForeignKeys checks that the foreign key constraints of a table are
+// mutual: each outbound foreign key must have a matching inbound reference on
+// the referenced table, and vice-versa. Unlike validation, it requires both
+// ends to agree on the origin and the referenced table. Foreign keys still in
+// the deprecated index representation are not checked; validation reports them.
+func checkForeignKeys(e *examination, ddg catalog.MapDescGetter, table catalog.TableDescriptor) {
+	if table.Dropped() {
+		return
+	}
+	tbl := table.TableDesc()
+	for i := range tbl.OutboundFKs {
+		fk := &tbl.OutboundFKs[i]
+		referenced := lookupTable(ddg, fk.ReferencedTableID)
+		if referenced == nil {
+			e.missingReport(table, DanglingForeignKey,
+				"foreign key %q references missing table %d", fk.Name, fk.ReferencedTableID)
+			continue
+		}
+		if !hasForeignKey(referenced.TableDesc().InboundFKs, fk.Name, tbl.ID, fk.ReferencedTableID) {
+			e.descReport(table, OneSidedForeignKey,
+				"foreign key %q to %s has no matching back-reference", fk.Name, descSubject(referenced))
+		}
+	}
+	for i := range tbl.InboundFKs {
+		backref := &tbl.InboundFKs[i]
+		origin := lookupTable(ddg, backref.OriginTableID)
+		if origin == nil {
+			e.missingReport(table, DanglingForeignKey,
+				"foreign key back-reference %q from missing table %d", backref.Name, backref.OriginTableID)
+			continue
+		}
+		if !hasForeignKey(origin.TableDesc().OutboundFKs, backref.Name, backref.OriginTableID, tbl.ID) {
+			e.descReport(table, OneSidedForeignKey,
+				"foreign key back-reference %q from %s has no matching foreign key", backref.Name, descSubject(origin))
+		}
+	}
+}
+
+// hasForeignKey returns true iff fks contains a foreign key constraint with the
+// given name, origin and referenced table.
+func hasForeignKey(
+	fks []descpb.ForeignKeyConstraint, name string, originID, referencedID descpb.ID,
+) bool {
+	for i := range fks {
+		fk := &fks[i]
+		if fk.Name == name && fk.OriginTableID == originID && fk.ReferencedTableID == referencedID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDependencies checks that view dependencies are mutual: each relation a
+// view depends on must list the view among those depending on it, and each
+// view depending on a relation must list it among its dependencies. Relations
+// depended on by something other than a view, such as sequences used by
+// tables, only need their dependents to exist.
+func checkDependencies(e *examination, ddg catalog.MapDescGetter, table catalog.TableDescriptor) {
+	if table.Dropped() {
+		return
+	}
+	tbl := table.TableDesc()
+	for _, id := range tbl.DependsOn {
+		dependedOn := lookupTable(ddg, id)
+		if dependedOn == nil {
+			e.missingReport(table, DanglingDependency, "depends on missing relation %d", id)
+			continue
+		}
+		if !hasDependent(dependedOn.TableDesc().DependedOnBy, tbl.ID) {
+			e.descReport(table, OneSidedDependency,
+				"depends on %s, which has no matching back-reference", descSubject(dependedOn))
+		}
+	}
+	for _, ref := range tbl.DependedOnBy {
+		dependent := lookupTable(ddg, ref.ID)
+		if dependent == nil {
+			e.missingReport(table, DanglingDependency, "depended on by missing relation %d", ref.ID)
+			continue
+		}
+		if !dependent.IsView() {
+			continue
+		}
+		if !containsID(dependent.TableDesc().DependsOn, tbl.ID) {
+			e.descReport(table, OneSidedDependency,
+				"depended on by %s, which doesn't depend on it", descSubject(dependent))
+		}
+	}
+}
+
+// hasDependent returns true iff refs contains a reference from the given ID.
+func hasDependent(refs []descpb.TableDescriptor_Reference, id descpb.ID) bool {
+	for i := range refs {
+		if refs[i].ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// containsID returns true iff ids contains id.
+func containsID(ids []descpb.ID, id descpb.ID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSequenceOwner checks that the column which owns a sequence, if any,
+// exists and lists the sequence among those it owns. The owning column need
+// not use the sequence, as is the case with ALTER SEQUENCE ... OWNED BY.
+func checkSequenceOwner(e *examination, ddg catalog.MapDescGetter, table catalog.TableDescriptor) {
+	if table.Dropped() || !table.IsSequence() {
+		return
+	}
+	owner := table.GetSequenceOpts().SequenceOwner
+	if owner.OwnerTableID == descpb.InvalidID {
+		return
+	}
+	ownerTable := lookupTable(ddg, owner.OwnerTableID)
+	if ownerTable == nil {
+		e.missingReport(table, InvalidSequenceOwner, "owned by missing table %d", owner.OwnerTableID)
+		return
+	}
+	col, err := ownerTable.FindColumnWithID(owner.OwnerColumnID)
+	if err != nil {
+		e.descReport(table, InvalidSequenceOwner,
+			"owned by missing column %d of %s", owner.OwnerColumnID, descSubject(ownerTable))
+		return
+	}
+	if !containsID(col.ColumnDesc().OwnsSequenceIds, table.GetID()) {
+		e.descReport(table, InvalidSequenceOwner,
+			"owned by column %q of %s, which doesn't list it as owned", col.GetName(), descSubject(ownerTable))
+	}
+}
+
+// checkDroppedReferences checks that a live table doesn't reference dropped
+// descriptors through its foreign keys, its view dependencies or the table
+// owning it, if it's a sequence. Dropping a descriptor should remove these
+// references first. References to missing descriptors are reported by the
+// other checks.
+func checkDroppedReferences(
+	e *examination, ddg catalog.MapDescGetter, table catalog.TableDescriptor,
+) {
+	if table.Dropped() {
+		return
+	}
+	dropped := func(id descpb.ID) catalog.Descriptor {
+		if desc, ok := ddg.Descriptors[id]; ok && desc.Dropped() {
+			return desc
+		}
+		return nil
+	}
+	tbl := table.TableDesc()
+	for i := range tbl.OutboundFKs {
+		fk := &tbl.OutboundFKs[i]
+		if desc := dropped(fk.ReferencedTableID); desc != nil {
+			e.descReport(table, DroppedReference,
+				"foreign key %q references dropped %s", fk.Name, descSubject(desc))
+		}
+	}
+	for _, id := range tbl.DependsOn {
+		if desc := dropped(id); desc != nil {
+			e.descReport(table, DroppedReference, "depends on dropped %s", descSubject(desc))
+		}
+	}
+	if table.IsSequence() {
+		if desc := dropped(table.GetSequenceOpts().SequenceOwner.OwnerTableID); desc != nil {
+			e.descReport(table, DroppedReference, "owned by dropped %s", descSubject(desc))
+		}
+	}
+}
+
+// checkTypeReferences checks that the columns of a table which have a
+// user-defined type reference an existing type descriptor of the right kind:
+// arrays reference the implicit array type, all others an enum.
+func checkTypeReferences(e *examination, ddg catalog.MapDescGetter, table catalog.TableDescriptor) {
+	if table.Dropped() {
+		return
+	}
+	for _, col := range table.DeletableColumns() {
+		// Columns without a type are reported by checkColumnTypes.
+		if col.GetType() == nil || !col.GetType().UserDefined() {
+			continue
+		}
+		id, err := typedesc.GetUserDefinedTypeDescID(col.GetType())
+		if err != nil {
+			e.descReport(table, InvalidTypeReference, "column %q: %v", col.GetName(), err)
+			continue
+		}
+		desc, ok := ddg.Descriptors[id]
+		if !ok {
+			e.missingReport(table, DanglingTypeReference,
+				"column %q references missing type %d", col.GetName(), id)
+			continue
+		}
+		typ, ok := desc.(catalog.TypeDescriptor)
+		if !ok {
+			e.descReport(table, InvalidTypeReference,
+				"column %q references %s, which is not a type", col.GetName(), descSubject(desc))
+			continue
+		}
+		isArray := col.GetType().Family() == types.ArrayFamily
+		if isAlias := typ.GetKind() == descpb.TypeDescriptor_ALIAS; isArray != isAlias {
+			e.descReport(table, InvalidTypeReference,
+				"column %q of type %s references %s of kind %s",
+				col.GetName(), col.GetType().SQLString(), descSubject(typ), typ.GetKind())
+		}
+	}
+}
+
+// checkTypeBackReferences checks that the descriptors which a type lists as
+// referencing it are existing tables.
+func checkTypeBackReferences(e *examination, ddg catalog.MapDescGetter, typ catalog.TypeDescriptor) {
+	if typ.Dropped() {
+		return
+	}
+	for i := 0; i < typ.NumReferencingDescriptors(); i++ {
+		id := typ.GetReferencingDescriptorID(i)
+		desc, ok := ddg.Descriptors[id]
+		if !ok {
+			e.missingReport(typ, DanglingTypeReference, "back-reference to missing table %d", id)
+			continue
+		}
+		if _, ok := desc.(catalog.TableDescriptor); !ok {
+			e.descReport(typ, InvalidTypeReference,
+				"back-reference to %s, which is not a table", descSubject(desc))
+		}
+	}
+}
+
+// checkInterleaves checks that the interleave references of a table's indexes
+// are mutual: the parent index of an interleaved index, which is its nearest
+// ancestor, must list it among the indexes interleaved into it, and each index
+// interleaved into an index must have that index as its parent. The other
+// ancestors only need to exist. Interleaving is no longer supported and
+// validation rejects interleaved indexes, but their references still need to
+// be consistent to migrate them.
+func checkInterleaves(e *examination, ddg catalog.MapDescGetter, table catalog.TableDescriptor) {
+	if table.Dropped() {
+		return
+	}
+	for _, idx := range table.AllIndexes() {
+		ancestors := idx.IndexDesc().Interleave.Ancestors
+		for i, ancestor := range ancestors {
+			ancestorTable := lookupTable(ddg, ancestor.TableID)
+			if ancestorTable == nil {
+				e.missingReport(table, DanglingInterleave,
+					"index %q is interleaved into index %d of missing table %d",
+					idx.GetName(), ancestor.IndexID, ancestor.TableID)
+				continue
+			}
+			ancestorIdx, _ := ancestorTable.FindIndexWithID(ancestor.IndexID)
+			if ancestorIdx == nil {
+				e.descReport(table, DanglingInterleave,
+					"index %q is interleaved into missing index %d of %s",
+					idx.GetName(), ancestor.IndexID, descSubject(ancestorTable))
+				continue
+			}
+			if i == len(ancestors)-1 &&
+				!hasInterleaveReference(ancestorIdx.IndexDesc().InterleavedBy, table.GetID(), idx.GetID()) {
+				e.descReport(table, OneSidedInterleave,
+					"index %q is interleaved into index %q (%d) of %s, which has no matching interleaved-by reference",
+					idx.GetName(), ancestorIdx.GetName(), ancestorIdx.GetID(), descSubject(ancestorTable))
+			}
+		}
+		for _, ref := range idx.IndexDesc().InterleavedBy {
+			child := lookupTable(ddg, ref.Table)
+			if child == nil {
+				e.missingReport(table, DanglingInterleave,
+					"index %q is interleaved by index %d of missing table %d",
+					idx.GetName(), ref.Index, ref.Table)
+				continue
+			}
+			childIdx, _ := child.FindIndexWithID(ref.Index)
+			if childIdx == nil {
+				e.descReport(table, DanglingInterleave,
+					"index %q is interleaved by missing index %d of %s",
+					idx.GetName(), ref.Index, descSubject(child))
+				continue
+			}
+			childAncestors := childIdx.IndexDesc().Interleave.Ancestors
+			if len(childAncestors) == 0 ||
+				childAncestors[len(childAncestors)-1].TableID != table.GetID() ||
+				childAncestors[len(childAncestors)-1].IndexID != idx.GetID() {
+				e.descReport(table, OneSidedInterleave,
+					"index %q is interleaved by index %q (%d) of %s, which isn't interleaved into it",
+					idx.GetName(), childIdx.GetName(), childIdx.GetID(), descSubject(child))
+			}
+		}
+	}
+}
+
+// hasInterleaveReference returns true iff refs contains a reference to the
+// index with the given ID of the table with the given ID.
+func hasInterleaveReference(
+	refs []descpb.ForeignKeyReference, tableID descpb.ID, indexID descpb.IndexID,
+) bool {
+	for _, ref := range refs {
+		if ref.Table == tableID && ref.Index == indexID {
+			return true
+		}
+	}
+	return false
+}
+
+// checkParentSchema checks that the parent schema of a table or type is either
+// the public schema or an existing, non-dropped schema of its parent database.
+// Temporary tables belong to temporary schemas, which have no descriptor, and
+// aren't checked.
+func checkParentSchema(e *examination, ddg catalog.MapDescGetter, desc catalog.Descriptor) {
+	id := desc.GetParentSchemaID()
+	if desc.Dropped() || id == keys.PublicSchemaID || id == descpb.InvalidID {
+		return
+	}
+	parent, ok := ddg.Descriptors[id]
+	if !ok {
+		e.missingReport(desc, InvalidParentSchema, "parent schema %d is missing", id)
+		return
+	}
+	schema, ok := parent.(catalog.SchemaDescriptor)
+	switch {
+	case !ok:
+		e.descReport(desc, InvalidParentSchema,
+			"parent schema ID %d refers to %s, which is not a schema", id, descSubject(parent))
+	case schema.Dropped():
+		e.descReport(desc, InvalidParentSchema, "parent %s is dropped", descSubject(schema))
+	case schema.GetParentID() != desc.GetParentID():
+		e.descReport(desc, InvalidParentSchema, "parent %s belongs to database %d rather than %d",
+			descSubject(schema), schema.GetParentID(), desc.GetParentID())
+	}
+}
+
+// checkSchemaParent checks that the parent of a schema is an existing database
+// which lists the schema, under the schema's name, in its schemas mapping.
+func checkSchemaParent(e *examination, ddg catalog.MapDescGetter, schema catalog.SchemaDescriptor) {
+	if schema.Dropped() {
+		return
+	}
+	parent, ok := ddg.Descriptors[schema.GetParentID()]
+	if !ok {
+		e.missingReport(schema, DanglingSchemaParent,
+			"parent database %d is missing", schema.GetParentID())
+		return
+	}
+	db, ok := parent.(catalog.DatabaseDescriptor)
+	if !ok {
+		e.descReport(schema, DanglingSchemaParent,
+			"parent %s is not a database", descSubject(parent))
+		return
+	}
+	listed, listedName := false, ""
+	_ = db.ForEachSchemaInfo(func(id descpb.ID, name string, isDropped bool) error {
+		if id == schema.GetID() && !isDropped {
+			listed, listedName = true, name
+		}
+		return nil
+	})
+	switch {
+	case !listed:
+		e.descReport(schema, OneSidedSchemaParent,
+			"parent %s doesn't list it among its schemas", descSubject(db))
+	case listedName != schema.GetName():
+		e.descReport(schema, OneSidedSchemaParent,
+			"parent %s lists it among its schemas under name %q", descSubject(db), listedName)
+	}
+}
+
+// checkSchemaEntries checks that the entries of a database's schemas mapping
+// refer to existing, non-dropped schemas of this database. The converse, that
+// each schema of the database is listed under its name, is checked along with
+// the parent of each schema.
+func checkSchemaEntries(e *examination, ddg catalog.MapDescGetter, db catalog.DatabaseDescriptor) {
+	if db.Dropped() {
+		return
+	}
+	schemas := db.DatabaseDesc().Schemas
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		info := schemas[name]
+		if info.Dropped {
+			continue
+		}
+		desc, ok := ddg.Descriptors[info.ID]
+		if !ok {
+			e.missingReport(db, DanglingSchemaEntry,
+				"schema entry %q refers to missing schema %d", name, info.ID)
+			continue
+		}
+		schema, ok := desc.(catalog.SchemaDescriptor)
+		switch {
+		case !ok:
+			e.descReport(db, DanglingSchemaEntry,
+				"schema entry %q refers to %s, which is not a schema", name, descSubject(desc))
+		case schema.Dropped():
+			e.descReport(db, DanglingSchemaEntry,
+				"schema entry %q refers to dropped %s", name, descSubject(schema))
+		case schema.GetParentID() != db.GetID():
+			e.descReport(db, OneSidedSchemaEntry,
+				"schema entry %q refers to %s, whose parent is database %d",
+				name, descSubject(schema), schema.GetParentID())
+		}
+	}
+}
+
+// maxParentDepth bounds the number of ancestors walked by checkParentCycles.
+// A table or type has at most two legitimate ancestors, its schema and its
+// database.
+const maxParentDepth = 8
+
+// checkParentCycles checks that desc isn't its own ancestor through the
+// parent IDs and parent schema IDs of the descriptors above it, which would
+// make code walking up the hierarchy loop forever. Chains of more than
+// maxParentDepth ancestors are reported too, with or without a cycle.
+func checkParentCycles(e *examination, ddg catalog.MapDescGetter, desc catalog.Descriptor) {
+	var path []descpb.ID
+	tooDeep := false
+	var walk func(d catalog.Descriptor)
+	walk = func(d catalog.Descriptor) {
+		path = append(path, d.GetID())
+		defer func() { path = path[:len(path)-1] }()
+		for _, id := range []descpb.ID{d.GetParentID(), d.GetParentSchemaID()} {
+			if id == descpb.InvalidID || id == keys.PublicSchemaID {
+				continue
+			}
+			if id == desc.GetID() {
+				cycle := make([]string, 0, len(path)+1)
+				for _, pathID := range append(path, id) {
+					cycle = append(cycle, fmt.Sprint(pathID))
+				}
+				e.descReport(desc, ParentCycle,
+					"is its own ancestor: %s", strings.Join(cycle, " -> "))
+				continue
+			}
+			// Cycles which desc isn't part of are reported by their members.
+			parent, ok := ddg.Descriptors[id]
+			if !ok || containsID(path, id) {
+				continue
+			}
+			if len(path) > maxParentDepth {
+				tooDeep = true
+				continue
+			}
+			walk(parent)
+		}
+	}
+	walk(desc)
+	if tooDeep {
+		e.descReport(desc, ParentCycle, "has a chain of more than %d ancestors", maxParentDepth)
+	}
+}
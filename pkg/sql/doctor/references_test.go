@@ -0,0 +1,559 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/typedesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckForeignKeys(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	fk := descpb.ForeignKeyConstraint{
+		Name:                "fk",
+		OriginTableID:       51,
+		OriginColumnIDs:     []descpb.ColumnID{1},
+		ReferencedTableID:   53,
+		ReferencedColumnIDs: []descpb.ColumnID{1},
+	}
+	// Validation only checks the foreign keys of tables which are valid on their
+	// own, which this check constraint prevents.
+	invalidCheck := []*descpb.TableDescriptor_CheckConstraint{
+		{Name: "chk", Expr: "col > 0", ColumnIDs: []descpb.ColumnID{9}},
+	}
+	referencedRow := func(backrefs ...descpb.ForeignKeyConstraint) doctor.DescriptorTableRow {
+		return doctor.DescriptorTableRow{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.ID = 53
+			tbl.Name = "u"
+			tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("u")
+			tbl.InboundFKs = backrefs
+		}))}
+	}
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "dangling foreign key",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.OutboundFKs = []descpb.ForeignKeyConstraint{fk}
+					tbl.Checks = invalidCheck
+				}))},
+				dbRow(t),
+			},
+			namespaceTable: doctor.NamespaceTable{tableNamespaceRow("t", 51), dbNamespaceRow},
+			expected: []string{
+				`dangling foreign key: relation "t" (51): foreign key "fk" references missing table 53`,
+			},
+		},
+		{
+			name: "one-sided foreign key",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.OutboundFKs = []descpb.ForeignKeyConstraint{fk}
+					tbl.Checks = invalidCheck
+				}))},
+				dbRow(t),
+				referencedRow(),
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("u", 53),
+			},
+			expected: []string{
+				`one-sided foreign key: relation "t" (51): foreign key "fk" to relation "u" (53) has no matching back-reference`,
+			},
+		},
+		{
+			// Validation accepts a back-reference with the right origin and name.
+			name: "mismatched back-reference",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.OutboundFKs = []descpb.ForeignKeyConstraint{fk}
+				}))},
+				dbRow(t),
+				referencedRow(func() descpb.ForeignKeyConstraint {
+					backref := fk
+					backref.ReferencedTableID = 54
+					return backref
+				}()),
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("u", 53),
+			},
+			expected: []string{
+				`one-sided foreign key: relation "t" (51): foreign key "fk" to relation "u" (53) has no matching back-reference`,
+			},
+		},
+		{
+			// Validation reports these, they aren't reported twice.
+			name: "validated foreign keys",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.OutboundFKs = []descpb.ForeignKeyConstraint{fk}
+				}))},
+				dbRow(t),
+				referencedRow(func() descpb.ForeignKeyConstraint {
+					backref := fk
+					backref.Name = "fk_other"
+					return backref
+				}()),
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("u", 53),
+			},
+		},
+	})
+}
+
+func TestCheckDependencies(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "view dependencies",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.DependedOnBy = []descpb.TableDescriptor_Reference{{ID: 54, ColumnIDs: []descpb.ColumnID{1}}}
+				}))},
+				dbRow(t),
+				{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "v"
+					tbl.ID = 53
+					tbl.ViewQuery = "SELECT col FROM db.public.t"
+					tbl.Families = nil
+					tbl.NextFamilyID = 0
+					tbl.PrimaryIndex = descpb.IndexDescriptor{}
+					tbl.NextIndexID = 0
+					tbl.DependsOn = []descpb.ID{51, 60}
+				}))},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("v", 53),
+			},
+			expected: []string{
+				`dangling dependency: relation "t" (51): depended on by missing relation 54`,
+				`one-sided dependency: relation "v" (53): depends on relation "t" (51), which has no matching back-reference`,
+				`dangling dependency: relation "v" (53): depends on missing relation 60`,
+			},
+		},
+	})
+}
+
+func TestCheckSequenceOwner(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "sequence ownership",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+				dbRow(t),
+				{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "s"
+					tbl.ID = 53
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("s")
+					tbl.SequenceOpts = &descpb.TableDescriptor_SequenceOpts{
+						Increment: 1, MinValue: 1, MaxValue: 100, Start: 1,
+						SequenceOwner: descpb.TableDescriptor_SequenceOpts_SequenceOwner{
+							OwnerTableID: 60, OwnerColumnID: 1,
+						},
+					}
+				}))},
+				{ID: 54, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "s2"
+					tbl.ID = 54
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("s2")
+					tbl.SequenceOpts = &descpb.TableDescriptor_SequenceOpts{
+						Increment: 1, MinValue: 1, MaxValue: 100, Start: 1,
+						SequenceOwner: descpb.TableDescriptor_SequenceOpts_SequenceOwner{
+							OwnerTableID: 51, OwnerColumnID: 1,
+						},
+					}
+				}))},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("s", 53), tableNamespaceRow("s2", 54),
+			},
+			expected: []string{
+				`sequence ownership: relation "s" (53): owned by missing table 60`,
+				`sequence ownership: relation "s2" (54): owned by column "col" of relation "t" (51), which doesn't list it as owned`,
+			},
+		},
+	})
+}
+
+func TestCheckDroppedReferences(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "dropped references",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.OutboundFKs = []descpb.ForeignKeyConstraint{{
+						Name:                "fk",
+						OriginTableID:       51,
+						OriginColumnIDs:     []descpb.ColumnID{1},
+						ReferencedTableID:   53,
+						ReferencedColumnIDs: []descpb.ColumnID{1},
+					}}
+				}))},
+				dbRow(t),
+				{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "u"
+					tbl.ID = 53
+					tbl.State = descpb.DescriptorState_DROP
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("u")
+					tbl.Columns[0].OwnsSequenceIds = []descpb.ID{55}
+					tbl.InboundFKs = []descpb.ForeignKeyConstraint{{
+						Name:                "fk",
+						OriginTableID:       51,
+						OriginColumnIDs:     []descpb.ColumnID{1},
+						ReferencedTableID:   53,
+						ReferencedColumnIDs: []descpb.ColumnID{1},
+					}}
+					tbl.DependedOnBy = []descpb.TableDescriptor_Reference{{ID: 54, ColumnIDs: []descpb.ColumnID{1}}}
+				}))},
+				{ID: 54, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "v"
+					tbl.ID = 54
+					tbl.ViewQuery = "SELECT col FROM db.public.u"
+					tbl.Families = nil
+					tbl.NextFamilyID = 0
+					tbl.PrimaryIndex = descpb.IndexDescriptor{}
+					tbl.NextIndexID = 0
+					tbl.DependsOn = []descpb.ID{53}
+				}))},
+				{ID: 55, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "s"
+					tbl.ID = 55
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("s")
+					tbl.SequenceOpts = &descpb.TableDescriptor_SequenceOpts{
+						Increment: 1, MinValue: 1, MaxValue: 100, Start: 1,
+						SequenceOwner: descpb.TableDescriptor_SequenceOpts_SequenceOwner{
+							OwnerTableID: 53, OwnerColumnID: 1,
+						},
+					}
+				}))},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("v", 54), tableNamespaceRow("s", 55),
+			},
+			expected: []string{
+				`dropped reference: relation "t" (51): foreign key "fk" references dropped relation "u" (53)`,
+				`dropped reference: relation "v" (54): depends on dropped relation "u" (53)`,
+				`dropped reference: relation "s" (55): owned by dropped relation "u" (53)`,
+			},
+		},
+	})
+}
+
+func TestCheckTypeReferences(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "type references",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					for _, col := range []descpb.ColumnDescriptor{
+						{Name: "e", ID: 2, Type: types.MakeEnum(typedesc.TypeIDToOID(54), typedesc.TypeIDToOID(55))},
+						{Name: "a", ID: 3, Type: types.MakeEnum(typedesc.TypeIDToOID(56), typedesc.TypeIDToOID(57))},
+						{Name: "w", ID: 4, Type: types.MakeEnum(typedesc.TypeIDToOID(52), typedesc.TypeIDToOID(53))},
+					} {
+						col.Nullable = true
+						tbl.Columns = append(tbl.Columns, col)
+						tbl.Families[0].ColumnNames = append(tbl.Families[0].ColumnNames, col.Name)
+						tbl.Families[0].ColumnIDs = append(tbl.Families[0].ColumnIDs, col.ID)
+						tbl.PrimaryIndex.StoreColumnNames = append(tbl.PrimaryIndex.StoreColumnNames, col.Name)
+						tbl.PrimaryIndex.StoreColumnIDs = append(tbl.PrimaryIndex.StoreColumnIDs, col.ID)
+					}
+					tbl.NextColumnID = 5
+				}))},
+				dbRow(t),
+				{
+					ID: 54,
+					DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Type{
+						Type: &descpb.TypeDescriptor{
+							Name:                     "typ",
+							ID:                       54,
+							ParentID:                 52,
+							ParentSchemaID:           keys.PublicSchemaID,
+							ArrayTypeID:              55,
+							Kind:                     descpb.TypeDescriptor_ENUM,
+							ReferencingDescriptorIDs: []descpb.ID{51, 99},
+						},
+					}}),
+				},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("typ", 54),
+			},
+			expected: []string{
+				`dangling type reference: relation "t" (51): column "a" references missing type 56`,
+				`invalid type reference: relation "t" (51): column "w" references database "db" (52), which is not a type`,
+				`dangling type reference: type "typ" (54): back-reference to missing table 99`,
+			},
+		},
+	})
+}
+
+func TestCheckInterleaves(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "interleaves",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.PrimaryIndex.Interleave.Ancestors = []descpb.InterleaveDescriptor_Ancestor{
+						{TableID: 60, IndexID: 1, SharedPrefixLen: 1},
+						{TableID: 53, IndexID: 1, SharedPrefixLen: 1},
+					}
+				}))},
+				dbRow(t),
+				{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name = "u"
+					tbl.ID = 53
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("u")
+					tbl.PrimaryIndex.InterleavedBy = []descpb.ForeignKeyReference{{Table: 54, Index: 1}}
+				}))},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51), dbNamespaceRow, tableNamespaceRow("u", 53),
+			},
+			expected: []string{
+				`dangling interleave: relation "t" (51): index "t_pkey" is interleaved into index 1 of missing table 60`,
+				`one-sided interleave: relation "t" (51): index "t_pkey" is interleaved into index "u_pkey" (1) of relation "u" (53), which has no matching interleaved-by reference`,
+				`dangling interleave: relation "u" (53): index "u_pkey" is interleaved by index 1 of missing table 54`,
+			},
+		},
+	})
+}
+
+func TestCheckParentSchema(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "parent schemas",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.UnexposedParentSchemaID = 60
+				}))},
+				{ID: 52, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+					Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52, Schemas: map[string]descpb.DatabaseDescriptor_SchemaInfo{
+						"sc": {ID: 57},
+					}},
+				}})},
+				{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name, tbl.ID, tbl.UnexposedParentSchemaID = "u", 53, 52
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("u")
+				}))},
+				{ID: 54, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name, tbl.ID, tbl.UnexposedParentSchemaID = "v", 54, 55
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("v")
+				}))},
+				{ID: 55, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Schema{
+					Schema: &descpb.SchemaDescriptor{Name: "dropped", ID: 55, ParentID: 52, State: descpb.DescriptorState_DROP},
+				}})},
+				{ID: 56, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name, tbl.ID, tbl.UnexposedParentSchemaID = "w", 56, 58
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("w")
+				}))},
+				{ID: 57, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Schema{
+					Schema: &descpb.SchemaDescriptor{Name: "sc", ID: 57, ParentID: 52},
+				}})},
+				{ID: 58, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Schema{
+					Schema: &descpb.SchemaDescriptor{Name: "sc", ID: 58, ParentID: 59},
+				}})},
+				{ID: 59, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+					Database: &descpb.DatabaseDescriptor{Name: "db2", ID: 59, Schemas: map[string]descpb.DatabaseDescriptor_SchemaInfo{
+						"sc": {ID: 58},
+					}},
+				}})},
+				{ID: 61, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+					tbl.Name, tbl.ID, tbl.UnexposedParentSchemaID = "x", 61, 57
+					tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("x")
+				}))},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 60, Name: "t"}, ID: 51},
+				dbNamespaceRow,
+				{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 52, Name: "u"}, ID: 53},
+				{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 55, Name: "v"}, ID: 54},
+				{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 58, Name: "w"}, ID: 56},
+				{NameInfo: descpb.NameInfo{ParentID: 52, Name: "sc"}, ID: 57},
+				{NameInfo: descpb.NameInfo{ParentID: 59, Name: "sc"}, ID: 58},
+				{NameInfo: descpb.NameInfo{Name: "db2"}, ID: 59},
+				{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 57, Name: "x"}, ID: 61},
+			},
+			expected: []string{
+				`parent schema: relation "t" (51): parent schema 60 is missing`,
+				`parent schema: relation "u" (53): parent schema ID 52 refers to database "db" (52), which is not a schema`,
+				`parent schema: relation "v" (54): parent schema "dropped" (55) is dropped`,
+				`parent schema: relation "w" (56): parent schema "sc" (58) belongs to database 59 rather than 52`,
+			},
+		},
+	})
+}
+
+func TestCheckSchemaParent(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "schema parents",
+			descTable: doctor.DescriptorTable{
+				dbRow(t),
+				{ID: 53, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Schema{
+					Schema: &descpb.SchemaDescriptor{Name: "sc1", ID: 53, ParentID: 60},
+				}})},
+				{ID: 54, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Schema{
+					Schema: &descpb.SchemaDescriptor{Name: "sc2", ID: 54, ParentID: 52},
+				}})},
+				{ID: 55, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Schema{
+					Schema: &descpb.SchemaDescriptor{Name: "sc3", ID: 55, ParentID: 56},
+				}})},
+				{ID: 56, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+					Database: &descpb.DatabaseDescriptor{Name: "db2", ID: 56, Schemas: map[string]descpb.DatabaseDescriptor_SchemaInfo{
+						"other": {ID: 55},
+					}},
+				}})},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				dbNamespaceRow,
+				{NameInfo: descpb.NameInfo{ParentID: 60, Name: "sc1"}, ID: 53},
+				{NameInfo: descpb.NameInfo{ParentID: 52, Name: "sc2"}, ID: 54},
+				{NameInfo: descpb.NameInfo{ParentID: 56, Name: "sc3"}, ID: 55},
+				{NameInfo: descpb.NameInfo{Name: "db2"}, ID: 56},
+			},
+			expected: []string{
+				`dangling schema parent: schema "sc1" (53): parent database 60 is missing`,
+				`one-sided schema parent: schema "sc2" (54): parent database "db" (52) doesn't list it among its schemas`,
+				`one-sided schema parent: schema "sc3" (55): parent database "db2" (56) lists it among its schemas under name "other"`,
+			},
+		},
+	})
+}
+
+func TestCheckSchemaEntries(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "schema entries",
+			descTable: doctor.DescriptorTable{
+				{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+				{ID: 52, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+					Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52, Schemas: map[string]descpb.DatabaseDescriptor_SchemaInfo{
+						"a": {ID: 53},
+						"b": {ID: 51},
+						"c": {ID: 54},
+						"d": {ID: 55},
+						"e": {ID: 57, Dropped: true},
+					}},
+				}})},
+				{ID: 54, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Schema{
+					Schema: &descpb.SchemaDescriptor{Name: "c", ID: 54, ParentID: 56},
+				}})},
+				{ID: 55, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Schema{
+					Schema: &descpb.SchemaDescriptor{Name: "d", ID: 55, ParentID: 52, State: descpb.DescriptorState_DROP},
+				}})},
+				{ID: 56, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+					Database: &descpb.DatabaseDescriptor{Name: "db2", ID: 56, Schemas: map[string]descpb.DatabaseDescriptor_SchemaInfo{
+						"c": {ID: 54},
+					}},
+				}})},
+			},
+			namespaceTable: doctor.NamespaceTable{
+				tableNamespaceRow("t", 51),
+				dbNamespaceRow,
+				{NameInfo: descpb.NameInfo{ParentID: 56, Name: "c"}, ID: 54},
+				{NameInfo: descpb.NameInfo{Name: "db2"}, ID: 56},
+			},
+			expected: []string{
+				`dangling schema entry: database "db" (52): schema entry "a" refers to missing schema 53`,
+				`dangling schema entry: database "db" (52): schema entry "b" refers to relation "t" (51), which is not a schema`,
+				`one-sided schema entry: database "db" (52): schema entry "c" refers to schema "c" (54), whose parent is database 56`,
+				`dangling schema entry: database "db" (52): schema entry "d" refers to dropped schema "d" (55)`,
+			},
+		},
+	})
+}
+
+func TestExamineParentCycles(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.UnexposedParentSchemaID = 53
+		}))},
+		{ID: 52, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+			Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+		}})},
+		{ID: 53, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Schema{
+			Schema: &descpb.SchemaDescriptor{Name: "schema", ID: 53, ParentID: 51},
+		}})},
+	}
+	// A chain of tables, each the parent of the previous one, which is too
+	// long for the first table.
+	for id := 60; id <= 68; id++ {
+		id := id
+		descTable = append(descTable, doctor.DescriptorTableRow{
+			ID: int64(id),
+			DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+				tbl.Name = fmt.Sprintf("t%d", id)
+				tbl.ID = descpb.ID(id)
+				tbl.ParentID = descpb.ID(id + 1)
+				if id == 68 {
+					tbl.ParentID = 52
+				}
+			})),
+		})
+	}
+
+	problems, err := doctor.DescriptorProblems(
+		context.Background(), descTable, nil /* namespaceTable */, nil /* jobsTable */)
+	require.NoError(t, err)
+	var actual []string
+	for _, p := range problems {
+		if p.Kind == doctor.ParentCycle {
+			actual = append(actual, fmt.Sprintf("%s: %s", p.Subject, p.Message))
+		}
+	}
+	require.Equal(t, []string{
+		`relation "t" (51): is its own ancestor: 51 -> 53 -> 51`,
+		`schema "schema" (53): is its own ancestor: 53 -> 51 -> 53`,
+		`relation "t60" (60): has a chain of more than 8 ancestors`,
+	}, actual)
+}
@@ -0,0 +1,221 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/catalogkv"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/lexbase"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/cockroachdb/errors"
+)
+
+// RepairAction is a corrective action for a problem found by the doctor.
+type RepairAction struct {
+	// Subject is the descriptor or namespace entry which the action repairs.
+	Subject
+	// Description describes the action in human-readable form.
+	Description string
+	// SQL is the statement which performs the action.
+	SQL string
+}
+
+// RepairOption configures Repair.
+type RepairOption func(*repairConfig)
+
+type repairConfig struct {
+	dryRun bool
+}
+
+// WithDryRun makes Repair write a description of each action instead of the
+// SQL statements which perform them.
+func WithDryRun() RepairOption {
+	return func(cfg *repairConfig) {
+		cfg.dryRun = true
+	}
+}
+
+// Repair determines corrective actions for those problems in the descriptor
+// and namespace tables which have a deterministic and lossless fix, and writes
+// the SQL statements which perform them to w, as a single transaction. Repair
+// doesn't apply anything itself. Currently, the actions consist of:
+//   - deleting namespace entries whose descriptor doesn't exist, which no
+//     descriptor can be using,
+//   - raising table ID counters which aren't greater than every ID allocated
+//     with them, which only skips IDs which would collide on allocation.
+//
+// Descriptors are upserted with their version incremented, so that leases
+// pick up the change, and without forcing, so that a descriptor which fails
+// validation is rejected rather than written. All other problems, as well as
+// descriptors whose ID isn't unique in the descriptor table, are left alone
+// and need to be diagnosed with Examine.
+func Repair(
+	ctx context.Context,
+	descTable DescriptorTable,
+	namespaceTable NamespaceTable,
+	w io.Writer,
+	opts ...RepairOption,
+) ([]RepairAction, error) {
+	var cfg repairConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	rowsPerID := make(map[int64]int, len(descTable))
+	for _, r := range descTable {
+		rowsPerID[r.ID]++
+	}
+	var actions []RepairAction
+	for _, r := range descTable {
+		if rowsPerID[r.ID] > 1 {
+			continue
+		}
+		action, err := repairIDCounters(r)
+		if err != nil {
+			return nil, err
+		}
+		if action != nil {
+			actions = append(actions, *action)
+		}
+	}
+	for _, row := range namespaceTable {
+		if _, found := rowsPerID[row.ID]; found {
+			continue
+		}
+		// Public and temporary schemas have no descriptors to begin with.
+		err := validateNamespaceRow(catalog.MapDescGetter{}, row)
+		if !errors.Is(err, catalog.ErrDescriptorNotFound) {
+			continue
+		}
+		actions = append(actions, RepairAction{
+			Subject:     nsSubject(row),
+			Description: "delete namespace entry of missing descriptor",
+			SQL: fmt.Sprintf("SELECT crdb_internal.unsafe_delete_namespace_entry(%d, %d, %s, %d);",
+				row.ParentID, row.ParentSchemaID, lexbase.EscapeSQLString(row.Name), row.ID),
+		})
+	}
+	writeRepairActions(w, actions, cfg.dryRun)
+	return actions, nil
+}
+
+// repairIDCounters returns an action upserting the table descriptor in r with
+// its stale ID counters raised, or nil if there are none.
+func repairIDCounters(r DescriptorTableRow) (*RepairAction, error) {
+	var d descpb.Descriptor
+	if err := protoutil.Unmarshal(r.DescBytes, &d); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal descriptor %d", r.ID)
+	}
+	b := catalogkv.NewBuilderWithMVCCTimestamp(&d, r.ModTime)
+	if b == nil {
+		return nil, nil
+	}
+	mut := b.BuildExistingMutable()
+	table, ok := mut.(catalog.TableDescriptor)
+	if !ok || table.IsSequence() || table.Dropped() || int64(table.GetID()) != r.ID {
+		return nil, nil
+	}
+	tbl := table.TableDesc()
+	var maxColumnID descpb.ColumnID
+	var maxFamilyID descpb.FamilyID
+	var maxIndexID descpb.IndexID
+	var maxMutationID descpb.MutationID
+	for i := range tbl.Columns {
+		if id := tbl.Columns[i].ID; id > maxColumnID {
+			maxColumnID = id
+		}
+	}
+	for i := range tbl.Families {
+		if id := tbl.Families[i].ID; id > maxFamilyID {
+			maxFamilyID = id
+		}
+	}
+	maxIndexID = tbl.PrimaryIndex.ID
+	for i := range tbl.Indexes {
+		if id := tbl.Indexes[i].ID; id > maxIndexID {
+			maxIndexID = id
+		}
+	}
+	for i := range tbl.Mutations {
+		m := &tbl.Mutations[i]
+		if m.MutationID > maxMutationID {
+			maxMutationID = m.MutationID
+		}
+		if col := m.GetColumn(); col != nil && col.ID > maxColumnID {
+			maxColumnID = col.ID
+		}
+		if idx := m.GetIndex(); idx != nil && idx.ID > maxIndexID {
+			maxIndexID = idx.ID
+		}
+	}
+
+	var raised []string
+	if len(tbl.Columns) > 0 && tbl.NextColumnID <= maxColumnID {
+		raised = append(raised, fmt.Sprintf("NextColumnID from %d to %d", tbl.NextColumnID, maxColumnID+1))
+		tbl.NextColumnID = maxColumnID + 1
+	}
+	if len(tbl.Families) > 0 && tbl.NextFamilyID <= maxFamilyID {
+		raised = append(raised, fmt.Sprintf("NextFamilyID from %d to %d", tbl.NextFamilyID, maxFamilyID+1))
+		tbl.NextFamilyID = maxFamilyID + 1
+	}
+	// Only physical tables have indexes, views have an empty primary index.
+	if table.IsPhysicalTable() && tbl.NextIndexID <= maxIndexID {
+		raised = append(raised, fmt.Sprintf("NextIndexID from %d to %d", tbl.NextIndexID, maxIndexID+1))
+		tbl.NextIndexID = maxIndexID + 1
+	}
+	if len(tbl.Mutations) > 0 && tbl.NextMutationID <= maxMutationID {
+		raised = append(raised, fmt.Sprintf("NextMutationID from %d to %d", tbl.NextMutationID, maxMutationID+1))
+		tbl.NextMutationID = maxMutationID + 1
+	}
+	if len(raised) == 0 {
+		return nil, nil
+	}
+	// Increment the version so that leases pick up the change. The modification
+	// time stays that of the version read, as the upsert requires one.
+	tbl.Version++
+
+	descBytes, err := protoutil.Marshal(mut.DescriptorProto())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal descriptor %d", r.ID)
+	}
+	return &RepairAction{
+		Subject:     descSubject(table),
+		Description: "raise " + strings.Join(raised, ", "),
+		SQL: fmt.Sprintf("SELECT crdb_internal.unsafe_upsert_descriptor(%d, decode('%s', 'hex'));",
+			r.ID, hex.EncodeToString(descBytes)),
+	}, nil
+}
+
+// writeRepairActions writes the actions to w, either as a SQL transaction or,
+// in dry-run mode, as a list of descriptions.
+func writeRepairActions(w io.Writer, actions []RepairAction, dryRun bool) {
+	if len(actions) == 0 {
+		return
+	}
+	if dryRun {
+		for _, a := range actions {
+			fmt.Fprintf(w, "  ParentID %3d, ParentSchemaID %2d: %s: would %s\n",
+				a.ParentID, a.ParentSchemaID, a.Subject, a.Description)
+		}
+		return
+	}
+	fmt.Fprintln(w, `BEGIN;`)
+	for _, a := range actions {
+		fmt.Fprintf(w, "-- %s: %s\n", a.Subject, a.Description)
+		fmt.Fprintln(w, a.SQL)
+	}
+	fmt.Fprintln(w, `COMMIT;`)
+}
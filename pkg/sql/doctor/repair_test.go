@@ -0,0 +1,96 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
+	"github.com/cockroachdb/cockroach/pkg/util/hlc"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepair(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.NextColumnID = 1
+			tbl.Version = 3
+		})), ModTime: hlc.Timestamp{WallTime: 1}},
+		{
+			ID: 52,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+				Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+			}}),
+		},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "gone"}, ID: 60},
+		{NameInfo: descpb.NameInfo{ParentID: 52, Name: "public"}, ID: 29},
+		{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		var buf bytes.Buffer
+		actions, err := doctor.Repair(
+			context.Background(), descTable, namespaceTable, &buf, doctor.WithDryRun())
+		require.NoError(t, err)
+		require.Len(t, actions, 2)
+		require.Equal(t, `  ParentID  52, ParentSchemaID 29: relation "t" (51): would raise NextColumnID from 1 to 2
+  ParentID  52, ParentSchemaID 29: namespace entry "gone" (60): would delete namespace entry of missing descriptor
+`, buf.String())
+	})
+
+	t.Run("sql", func(t *testing.T) {
+		var buf bytes.Buffer
+		actions, err := doctor.Repair(context.Background(), descTable, namespaceTable, &buf)
+		require.NoError(t, err)
+		require.Len(t, actions, 2)
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		require.Len(t, lines, 6)
+		require.Equal(t, "BEGIN;", lines[0])
+		require.Equal(t, `-- relation "t" (51): raise NextColumnID from 1 to 2`, lines[1])
+		const prefix, suffix = "SELECT crdb_internal.unsafe_upsert_descriptor(51, decode('", "', 'hex'));"
+		require.True(t, strings.HasPrefix(lines[2], prefix), lines[2])
+		require.True(t, strings.HasSuffix(lines[2], suffix), lines[2])
+		descBytes, err := hex.DecodeString(strings.TrimSuffix(strings.TrimPrefix(lines[2], prefix), suffix))
+		require.NoError(t, err)
+		var desc descpb.Descriptor
+		require.NoError(t, protoutil.Unmarshal(descBytes, &desc))
+		tbl, _, _, _ := descpb.FromDescriptor(&desc)
+		require.Equal(t, descpb.ColumnID(2), tbl.NextColumnID)
+		require.Equal(t, descpb.DescriptorVersion(4), tbl.Version)
+		require.Equal(t, hlc.Timestamp{WallTime: 1}, tbl.ModificationTime)
+		require.Equal(t, `-- namespace entry "gone" (60): delete namespace entry of missing descriptor`, lines[3])
+		require.Equal(t, "SELECT crdb_internal.unsafe_delete_namespace_entry(52, 29, 'gone', 60);", lines[4])
+		require.Equal(t, "COMMIT;", lines[5])
+	})
+
+	t.Run("nothing to repair", func(t *testing.T) {
+		var buf bytes.Buffer
+		actions, err := doctor.Repair(
+			context.Background(), doctor.DescriptorTable{descTable[1]}, namespaceTable[3:], &buf)
+		require.NoError(t, err)
+		require.Empty(t, actions)
+		require.Empty(t, buf.String())
+	})
+}
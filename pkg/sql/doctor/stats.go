@@ -0,0 +1,64 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+)
+
+// descriptorSizes accumulates the serialized sizes of the descriptors of each
+// kind.
+type descriptorSizes map[DescriptorKind]*sizeStats
+
+// sizeStats summarizes the serialized sizes of some descriptors.
+type sizeStats struct {
+	count, min, max, total int
+	// largestID is the ID of the descriptor of size max.
+	largestID descpb.ID
+}
+
+// add records a descriptor of the given kind, ID and size.
+func (s descriptorSizes) add(kind DescriptorKind, id descpb.ID, size int) {
+	stats, ok := s[kind]
+	if !ok {
+		stats = &sizeStats{min: size, max: size, largestID: id}
+		s[kind] = stats
+	}
+	stats.count++
+	stats.total += size
+	if size < stats.min {
+		stats.min = size
+	}
+	if size > stats.max {
+		stats.max, stats.largestID = size, id
+	}
+}
+
+// write writes the number of descriptors of each kind and their sizes.
+func (s descriptorSizes) write(w io.Writer) {
+	total := 0
+	for _, stats := range s {
+		total += stats.total
+	}
+	_, _ = fmt.Fprintf(w, "Descriptor sizes, %d bytes in total:\n", total)
+	for kind := DescriptorKindTable; kind <= DescriptorKindSequence; kind++ {
+		stats, ok := s[kind]
+		if !ok {
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "  %s: %d %s, %d to %d bytes (largest: %d), %d bytes in total\n",
+			kind, stats.count, pluralize(stats.count, "descriptor"),
+			stats.min, stats.max, stats.largestID, stats.total)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/tabledesc"
+	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExamineSizeStats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	descTable := doctor.DescriptorTable{
+		{ID: 51, DescBytes: toBytes(t, validTableDesc)},
+		{
+			ID: 52,
+			DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Database{
+				Database: &descpb.DatabaseDescriptor{Name: "db", ID: 52},
+			}}),
+		},
+		{ID: 53, DescBytes: toBytes(t, modifiedTableDesc(func(tbl *descpb.TableDescriptor) {
+			tbl.Name = "longer_name"
+			tbl.ID = 53
+			tbl.PrimaryIndex.Name = tabledesc.PrimaryKeyIndexName("longer_name")
+		}))},
+	}
+	namespaceTable := doctor.NamespaceTable{
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "t"}, ID: 51},
+		{NameInfo: descpb.NameInfo{Name: "db"}, ID: 52},
+		{NameInfo: descpb.NameInfo{ParentID: 52, ParentSchemaID: 29, Name: "longer_name"}, ID: 53},
+	}
+	t1, db, t2 := len(descTable[0].DescBytes), len(descTable[1].DescBytes), len(descTable[2].DescBytes)
+	require.Less(t, t1, t2)
+
+	var buf bytes.Buffer
+	valid, err := doctor.ExamineDescriptors(
+		context.Background(), descTable, namespaceTable, nil /* jobsTable */, false, &buf,
+		doctor.WithSizeStats())
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.Equal(t, fmt.Sprintf(`Examining 3 descriptors and 3 namespace entries...
+Descriptor sizes, %d bytes in total:
+  table: 2 descriptors, %d to %d bytes (largest: 53), %d bytes in total
+  database: 1 descriptor, %d to %d bytes (largest: 52), %d bytes in total
+`, t1+db+t2, t1, t2, t1+t2, db, db, db), buf.String())
+}
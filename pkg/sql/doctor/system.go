@@ -0,0 +1,123 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/pkg/config/zonepb"
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/bootstrap"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+)
+
+// bootstrapSystemTables returns the system tables created when bootstrapping a
+// cluster at this version, indexed by ID.
+func bootstrapSystemTables() map[descpb.ID]catalog.TableDescriptor {
+	ms := bootstrap.MakeMetadataSchema(
+		keys.SystemSQLCodec, zonepb.DefaultZoneConfigRef(), zonepb.DefaultSystemZoneConfigRef(),
+	)
+	tables := make(map[descpb.ID]catalog.TableDescriptor)
+	_ = ms.ForEachCatalogDescriptor(func(desc catalog.Descriptor) error {
+		if table, ok := desc.(catalog.TableDescriptor); ok {
+			tables[table.GetID()] = table
+		}
+		return nil
+	})
+	return tables
+}
+
+// checkSystemTable compares desc, which has the ID of a system table, with the
+// expected definition of that table: its name, the name, type and nullability
+// of its columns, the name, uniqueness and key columns of its indexes and the
+// privileges of its users must all match.
+func checkSystemTable(e *examination, desc catalog.Descriptor, expected catalog.TableDescriptor) {
+	table, ok := desc.(catalog.TableDescriptor)
+	if !ok {
+		e.descReport(desc, SystemSchemaMismatch,
+			"has the ID of system table %q, but isn't a table", expected.GetName())
+		return
+	}
+	mismatch := func(field string, found, want interface{}) {
+		if found != want {
+			e.descReport(table, SystemSchemaMismatch,
+				"%s of system table %q is %v, expected %v", field, expected.GetName(), found, want)
+		}
+	}
+	mismatch("name", table.GetName(), expected.GetName())
+
+	for _, want := range expected.PublicColumns() {
+		col, err := table.FindColumnWithID(want.GetID())
+		if err != nil {
+			e.descReport(table, SystemSchemaMismatch, "column %q (%d) of system table %q is missing",
+				want.GetName(), want.GetID(), expected.GetName())
+			continue
+		}
+		field := fmt.Sprintf("column %d", want.GetID())
+		mismatch(field+" name", col.GetName(), want.GetName())
+		if col.GetType() != nil {
+			mismatch(field+" type", col.GetType().SQLString(), want.GetType().SQLString())
+		}
+		mismatch(field+" nullability", col.IsNullable(), want.IsNullable())
+	}
+	for _, col := range table.PublicColumns() {
+		if _, err := expected.FindColumnWithID(col.GetID()); err != nil {
+			e.descReport(table, SystemSchemaMismatch, "column %q (%d) isn't part of system table %q",
+				col.GetName(), col.GetID(), expected.GetName())
+		}
+	}
+
+	for _, want := range expected.ActiveIndexes() {
+		idx, err := table.FindIndexWithID(want.GetID())
+		if err != nil {
+			e.descReport(table, SystemSchemaMismatch, "index %q (%d) of system table %q is missing",
+				want.GetName(), want.GetID(), expected.GetName())
+			continue
+		}
+		field := fmt.Sprintf("index %d", want.GetID())
+		mismatch(field+" name", idx.GetName(), want.GetName())
+		mismatch(field+" uniqueness", idx.IsUnique(), want.IsUnique())
+		mismatch(field+" key columns",
+			fmt.Sprint(idx.IndexDesc().KeyColumnIDs), fmt.Sprint(want.IndexDesc().KeyColumnIDs))
+	}
+	for _, idx := range table.ActiveIndexes() {
+		if _, err := expected.FindIndexWithID(idx.GetID()); err != nil {
+			e.descReport(table, SystemSchemaMismatch, "index %q (%d) isn't part of system table %q",
+				idx.GetName(), idx.GetID(), expected.GetName())
+		}
+	}
+
+	privs, wantPrivs := table.GetPrivileges(), expected.GetPrivileges()
+	for _, want := range wantPrivs.Users {
+		var found uint32
+		if u, ok := privs.FindUser(want.User()); ok {
+			found = u.Privileges
+		}
+		mismatch(fmt.Sprintf("privileges of user %s", want.User()),
+			systemPrivilegeString(found), systemPrivilegeString(want.Privileges))
+	}
+	for _, u := range privs.Users {
+		if _, ok := wantPrivs.FindUser(u.User()); !ok {
+			mismatch(fmt.Sprintf("privileges of user %s", u.User()),
+				systemPrivilegeString(u.Privileges), systemPrivilegeString(0))
+		}
+	}
+}
+
+// systemPrivilegeString formats a privilege bit field like privilegeString,
+// except that no privileges at all are formatted as "none".
+func systemPrivilegeString(bits uint32) string {
+	if bits == 0 {
+		return "none"
+	}
+	return privilegeString(bits)
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 The Cockroach Authors.
+//
+// Use of this software is governed by the Business Source License
+// included in the file licenses/BSL.txt.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the Apache License, Version 2.0, included in the file
+// licenses/APL.txt.
+
+package doctor_test
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/pkg/keys"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/descpb"
+	"github.com/cockroachdb/cockroach/pkg/sql/catalog/systemschema"
+	"github.com/cockroachdb/cockroach/pkg/sql/doctor"
+	"github.com/cockroachdb/cockroach/pkg/sql/types"
+	"github.com/cockroachdb/cockroach/pkg/util/leaktest"
+	"github.com/cockroachdb/cockroach/pkg/util/log"
+	"github.com/cockroachdb/cockroach/pkg/util/protoutil"
+)
+
+func TestCheckSystemTable(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer log.Scope(t).Close(t)
+
+	runCheckTests(t, []checkTest{
+		{
+			name: "system schema",
+			descTable: doctor.DescriptorTable{
+				{ID: keys.SqllivenessID, DescBytes: toBytes(t, func() *descpb.Descriptor {
+					tbl := protoutil.Clone(systemschema.SqllivenessTable.TableDesc()).(*descpb.TableDescriptor)
+					tbl.Columns[1].Type = types.Int
+					tbl.Columns[1].Nullable = true
+					tbl.PrimaryIndex.Name = "sqlliveness_pkey"
+					return &descpb.Descriptor{Union: &descpb.Descriptor_Table{Table: tbl}}
+				}())},
+				{ID: keys.MigrationsID, DescBytes: toBytes(t, &descpb.Descriptor{Union: &descpb.Descriptor_Table{
+					Table: protoutil.Clone(systemschema.MigrationsTable.TableDesc()).(*descpb.TableDescriptor),
+				}})},
+			},
+			opts: []doctor.ExamineOption{doctor.WithSystemSchemaCheck()},
+			expected: []string{
+				`system schema mismatch: relation "sqlliveness" (39): column 2 type of system table "sqlliveness" is INT8, expected DECIMAL`,
+				`system schema mismatch: relation "sqlliveness" (39): column 2 nullability of system table "sqlliveness" is true, expected false`,
+				`system schema mismatch: relation "sqlliveness" (39): index 1 name of system table "sqlliveness" is sqlliveness_pkey, expected primary`,
+			},
+		},
+	})
+}